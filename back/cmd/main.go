@@ -4,6 +4,7 @@ import (
 	"ahsfnu-media-cloud/internal/api"
 	"ahsfnu-media-cloud/internal/config"
 	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/services"
 	"log"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,16 @@ func main() {
 	// 初始化数据库
 	database.Init()
 
+	// 在后台为尚未建立检索索引的素材补建索引，不阻塞服务启动
+	go func() {
+		if err := services.IndexMissingMaterials(database.GetDB()); err != nil {
+			log.Println("Failed to build search index:", err)
+		}
+	}()
+
+	// 定期归档到期的工作流
+	services.StartWorkflowArchiver(database.GetDB(), services.WorkflowArchiveInterval)
+
 	r := gin.Default()
 
 	// 设置路由
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/services"
+	"log"
+)
+
+// 主密钥轮换：将 ENCRYPTION_MASTER_KEY 设为新密钥、ENCRYPTION_MASTER_KEY_ID 设为新标识，
+// 并把旧密钥加入 ENCRYPTION_PREVIOUS_KEYS 后运行本命令。
+// 只重新包装各文件的数据密钥，不会重新加密文件内容。
+func main() {
+	// 初始化配置
+	config.Init()
+
+	// 初始化数据库
+	database.Init()
+
+	rotated, err := services.RotateDataKeys(database.GetDB())
+	if err != nil {
+		log.Fatalf("Key rotation failed after %d materials: %v", rotated, err)
+	}
+	log.Printf("Key rotation completed, %d data keys re-wrapped", rotated)
+}
@@ -0,0 +1,662 @@
+package album
+
+import (
+	"net/http"
+	"strconv"
+
+	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type albumCollaboratorRequest struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type albumRequest struct {
+	Name            string                      `json:"name"`
+	Description     *string                     `json:"description"`
+	CoverMaterialID *uint                       `json:"cover_material_id"` // 为 0 表示使用第一个素材作为封面
+	Collaborators   *[]albumCollaboratorRequest `json:"collaborators"`
+	MaterialIDs     []uint                      `json:"material_ids"` // 创建时的初始素材
+}
+
+type albumItemsRequest struct {
+	MaterialIDs []uint `json:"material_ids"`
+	Position    *int   `json:"position"` // 插入位置（从 0 开始），未指定时追加到末尾
+}
+
+// canViewAlbum 创建者、管理员和协作者可以查看
+func canViewAlbum(album *models.Album, userID uint, role string) bool {
+	return album.CreatedBy == userID || role == "admin" || album.CollaboratorRole(userID) != ""
+}
+
+// canEditAlbum 创建者、管理员和编辑者可以修改相册内容
+func canEditAlbum(album *models.Album, userID uint, role string) bool {
+	return album.CreatedBy == userID || role == "admin" || album.CollaboratorRole(userID) == models.AlbumRoleEditor
+}
+
+// getAlbum 按路径参数获取相册，失败时写入错误响应
+func (s *AlbumService) getAlbum(c *gin.Context) (*models.Album, bool) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的相册ID"})
+		return nil, false
+	}
+	var album models.Album
+	if err := s.db.Preload("Creator").Preload("Collaborators.User").First(&album, albumID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "相册不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取相册失败"})
+		return nil, false
+	}
+	return &album, true
+}
+
+// albumResponse 转换为响应格式，附带查看者可见的素材数量与封面缩略图
+// 指定的封面对查看者不可见时，使用第一个可见的素材作为封面
+func (s *AlbumService) albumResponse(album *models.Album, userID uint, role string) *models.AlbumResponse {
+	response := album.ToAlbumResponse()
+	response.CanEdit = canEditAlbum(album, userID, role)
+
+	visible := materials.NewMaterialQueryBuilder(s.db).WithVisibleTo(userID, role).Build().Select("materials.id")
+	visibleItems := func() *gorm.DB {
+		return s.db.Model(&models.Material{}).
+			Joins("JOIN album_items ON album_items.material_id = materials.id AND album_items.album_id = ?", album.ID).
+			Where("materials.id IN (?)", visible)
+	}
+	visibleItems().Count(&response.ItemCount)
+
+	var cover models.Material
+	found := false
+	if album.CoverMaterialID != nil {
+		found = visibleItems().Where("materials.id = ?", *album.CoverMaterialID).First(&cover).Error == nil
+	}
+	if !found {
+		found = visibleItems().Order("album_items.position, album_items.id").First(&cover).Error == nil
+	}
+	if found && cover.ThumbnailPath != "" {
+		response.CoverThumbnail = s.uploadService.GetThumbnailURL(&cover)
+	}
+	return response
+}
+
+// visibleMaterialIDs 过滤出用户可见的素材ID，保持传入顺序并去重
+func (s *AlbumService) visibleMaterialIDs(ids []uint, userID uint, role string) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var found []uint
+	visible := materials.NewMaterialQueryBuilder(s.db).WithVisibleTo(userID, role).Build().Select("materials.id")
+	err := s.db.Model(&models.Material{}).
+		Where("id IN ? AND id IN (?)", ids, visible).
+		Pluck("id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	result := []uint{}
+	for _, id := range ids {
+		if exists[id] {
+			result = append(result, id)
+			delete(exists, id)
+		}
+	}
+	return result, nil
+}
+
+// replaceCollaborators 重新设置协作者，忽略创建者本人
+func replaceCollaborators(tx *gorm.DB, album *models.Album, collaborators []albumCollaboratorRequest) error {
+	if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumCollaborator{}).Error; err != nil {
+		return err
+	}
+	seen := make(map[uint]bool)
+	for _, collaborator := range collaborators {
+		if collaborator.UserID == album.CreatedBy || seen[collaborator.UserID] {
+			continue
+		}
+		seen[collaborator.UserID] = true
+		role := collaborator.Role
+		if role == "" {
+			role = models.AlbumRoleViewer
+		}
+		err := tx.Create(&models.AlbumCollaborator{AlbumID: album.ID, UserID: collaborator.UserID, Role: role}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCollaborators 检查协作者角色与用户是否存在
+func (s *AlbumService) validateCollaborators(collaborators []albumCollaboratorRequest) (string, bool) {
+	userIDs := make([]uint, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		if collaborator.Role != "" && collaborator.Role != models.AlbumRoleEditor && collaborator.Role != models.AlbumRoleViewer {
+			return "协作者角色只能是 editor 或 viewer", false
+		}
+		userIDs = append(userIDs, collaborator.UserID)
+	}
+	if len(userIDs) == 0 {
+		return "", true
+	}
+	var count int64
+	s.db.Model(&models.User{}).Where("id IN ?", userIDs).Distinct("id").Count(&count)
+	if int(count) != len(uniqueIDs(userIDs)) {
+		return "协作者用户不存在", false
+	}
+	return "", true
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// insertItems 将素材插入相册的指定位置，已在相册中的素材会被跳过
+func insertItems(tx *gorm.DB, albumID uint, materialIDs []uint, position *int, userID uint) (int, error) {
+	var existing []uint
+	if err := tx.Model(&models.AlbumItem{}).Where("album_id = ? AND material_id IN ?", albumID, materialIDs).
+		Pluck("material_id", &existing).Error; err != nil {
+		return 0, err
+	}
+	inAlbum := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		inAlbum[id] = true
+	}
+	var added []uint
+	for _, id := range materialIDs {
+		if !inAlbum[id] {
+			added = append(added, id)
+		}
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	var start int
+	if position != nil {
+		start = *position
+		// 为插入的素材腾出位置
+		err := tx.Model(&models.AlbumItem{}).Where("album_id = ? AND position >= ?", albumID, start).
+			Update("position", gorm.Expr("position + ?", len(added))).Error
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		var maxPosition *int
+		tx.Model(&models.AlbumItem{}).Where("album_id = ?", albumID).Select("MAX(position)").Scan(&maxPosition)
+		if maxPosition != nil {
+			start = *maxPosition + 1
+		}
+	}
+	for i, id := range added {
+		item := models.AlbumItem{AlbumID: albumID, MaterialID: id, Position: start + i, AddedBy: userID}
+		if err := tx.Create(&item).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(added), nil
+}
+
+// GetAlbums 获取自己创建及参与协作的相册
+func GetAlbums(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	uid := userID.(uint)
+
+	query := service.db.Preload("Creator").Preload("Collaborators.User").
+		Where("created_by = ? OR id IN (SELECT album_id FROM album_collaborators WHERE user_id = ?)", uid, uid)
+	// 按包含的素材查询所在相册
+	if materialID := c.Query("material_id"); materialID != "" {
+		query = query.Where("id IN (SELECT album_id FROM album_items WHERE material_id = ?)", materialID)
+	}
+
+	var albums []models.Album
+	if err := query.Order("updated_at DESC").Find(&albums).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取相册列表失败"})
+		return
+	}
+
+	responses := []models.AlbumResponse{}
+	for i := range albums {
+		responses = append(responses, *service.albumResponse(&albums[i], uid, userRole.(string)))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// CreateAlbum 创建相册，可同时指定协作者与初始素材
+func CreateAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	uid := userID.(uint)
+
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "相册名称不能为空"})
+		return
+	}
+	if req.Collaborators != nil {
+		if message, ok := service.validateCollaborators(*req.Collaborators); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+	}
+	materialIDs, err := service.visibleMaterialIDs(req.MaterialIDs, uid, userRole.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取素材失败"})
+		return
+	}
+	if len(materialIDs) != len(uniqueIDs(req.MaterialIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "素材不存在或没有权限查看"})
+		return
+	}
+
+	album := models.Album{
+		Name:      req.Name,
+		CreatedBy: uid,
+	}
+	if req.Description != nil {
+		album.Description = *req.Description
+	}
+	if req.CoverMaterialID != nil && *req.CoverMaterialID != 0 {
+		if !containsID(materialIDs, *req.CoverMaterialID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "封面必须是相册中的素材"})
+			return
+		}
+		album.CoverMaterialID = req.CoverMaterialID
+	}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&album).Error; err != nil {
+			return err
+		}
+		if req.Collaborators != nil {
+			if err := replaceCollaborators(tx, &album, *req.Collaborators); err != nil {
+				return err
+			}
+		}
+		if len(materialIDs) > 0 {
+			if _, err := insertItems(tx, album.ID, materialIDs, nil, uid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建相册失败"})
+		return
+	}
+
+	service.db.Preload("Creator").Preload("Collaborators.User").First(&album, album.ID)
+	c.JSON(http.StatusCreated, service.albumResponse(&album, uid, userRole.(string)))
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAlbum 获取相册详情
+func GetAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canViewAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看此相册"})
+		return
+	}
+	c.JSON(http.StatusOK, service.albumResponse(album, userID.(uint), userRole.(string)))
+}
+
+// UpdateAlbum 更新相册信息与封面；协作者只能由创建者或管理员修改
+func UpdateAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	uid, role := userID.(uint), userRole.(string)
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canEditAlbum(album, uid, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限修改此相册"})
+		return
+	}
+
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.CoverMaterialID != nil {
+		if *req.CoverMaterialID == 0 {
+			updates["cover_material_id"] = nil
+		} else {
+			var count int64
+			service.db.Model(&models.AlbumItem{}).Where("album_id = ? AND material_id = ?", album.ID, *req.CoverMaterialID).Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "封面必须是相册中的素材"})
+				return
+			}
+			updates["cover_material_id"] = *req.CoverMaterialID
+		}
+	}
+	if req.Collaborators != nil {
+		if album.CreatedBy != uid && role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有相册创建者可以管理协作者"})
+			return
+		}
+		if message, ok := service.validateCollaborators(*req.Collaborators); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(album).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Collaborators != nil {
+			return replaceCollaborators(tx, album, *req.Collaborators)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新相册失败"})
+		return
+	}
+
+	var updated models.Album
+	service.db.Preload("Creator").Preload("Collaborators.User").First(&updated, album.ID)
+	c.JSON(http.StatusOK, service.albumResponse(&updated, uid, role))
+}
+
+// DeleteAlbum 删除相册（仅创建者或管理员），相册中的素材不受影响
+func DeleteAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if album.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限删除此相册"})
+		return
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumCollaborator{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(album).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除相册失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "相册删除成功"})
+}
+
+// GetAlbumMaterials 按手动顺序获取相册中查看者可见的素材
+func GetAlbumMaterials(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canViewAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看此相册"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := materials.NewMaterialQueryBuilder(service.db).
+		WithVisibleTo(userID.(uint), userRole.(string)).
+		Build().
+		Joins("JOIN album_items ON album_items.material_id = materials.id AND album_items.album_id = ?", album.ID)
+
+	var items []models.Material
+	var total int64
+	query.Count(&total)
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).
+		Order("album_items.position, album_items.id").
+		Find(&items).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取相册素材失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": materials.MaterialResponses(items),
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// AddAlbumMaterials 向相册添加素材，素材所属工作流不变
+func AddAlbumMaterials(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	uid, role := userID.(uint), userRole.(string)
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canEditAlbum(album, uid, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限修改此相册"})
+		return
+	}
+
+	var req albumItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.MaterialIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要添加的素材"})
+		return
+	}
+	if req.Position != nil && *req.Position < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的插入位置"})
+		return
+	}
+	materialIDs, err := service.visibleMaterialIDs(req.MaterialIDs, uid, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取素材失败"})
+		return
+	}
+	if len(materialIDs) != len(uniqueIDs(req.MaterialIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "素材不存在或没有权限查看"})
+		return
+	}
+
+	var added int
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if added, err = insertItems(tx, album.ID, materialIDs, req.Position, uid); err != nil {
+			return err
+		}
+		return tx.Model(album).Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加相册素材失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "添加成功", "added": added})
+}
+
+// RemoveAlbumMaterial 从相册移除素材，移除的是封面时恢复为默认封面
+func RemoveAlbumMaterial(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canEditAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限修改此相册"})
+		return
+	}
+	materialID, err := strconv.ParseUint(c.Param("materialId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的素材ID"})
+		return
+	}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("album_id = ? AND material_id = ?", album.ID, materialID).Delete(&models.AlbumItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		updates := map[string]interface{}{"updated_at": gorm.Expr("CURRENT_TIMESTAMP")}
+		if album.CoverMaterialID != nil && *album.CoverMaterialID == uint(materialID) {
+			updates["cover_material_id"] = nil
+		}
+		return tx.Model(album).Updates(updates).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "素材不在此相册中"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除相册素材失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "移除成功"})
+}
+
+// ReorderAlbumMaterials 调整相册素材顺序：列出的素材按给定顺序排在最前，其余素材保持原有相对顺序
+func ReorderAlbumMaterials(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getAlbum(c)
+	if !ok {
+		return
+	}
+	if !canEditAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限修改此相册"})
+		return
+	}
+
+	var req albumItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var items []models.AlbumItem
+	if err := service.db.Where("album_id = ?", album.ID).Order("position, id").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取相册素材失败"})
+		return
+	}
+	byMaterial := make(map[uint]*models.AlbumItem, len(items))
+	for i := range items {
+		byMaterial[items[i].MaterialID] = &items[i]
+	}
+
+	ordered := make([]*models.AlbumItem, 0, len(items))
+	listed := make(map[uint]bool)
+	for _, id := range req.MaterialIDs {
+		item, ok := byMaterial[id]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "素材 " + strconv.FormatUint(uint64(id), 10) + " 不在此相册中"})
+			return
+		}
+		if !listed[id] {
+			listed[id] = true
+			ordered = append(ordered, item)
+		}
+	}
+	for i := range items {
+		if !listed[items[i].MaterialID] {
+			ordered = append(ordered, &items[i])
+		}
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		for position, item := range ordered {
+			if item.Position == position {
+				continue
+			}
+			if err := tx.Model(item).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(album).Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "调整相册顺序失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "顺序已更新"})
+}
@@ -0,0 +1,502 @@
+package album
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AlbumService struct {
+	db            *gorm.DB
+	uploadService *services.UploadService
+}
+
+func NewAlbumService() *AlbumService {
+	return &AlbumService{
+		db:            database.GetDB(),
+		uploadService: services.NewUploadService(),
+	}
+}
+
+// 查看智能相册时可由查看者指定的分页与排序参数
+var smartAlbumViewParams = []string{"page", "page_size", "cursor", "sort_by", "sort_order"}
+
+// 每次都需要实时搜索计算新增数量：列表中最多为这么多个开启角标的相册计算，
+// 其余相册不返回 new_count；计算结果缓存一段时间
+const (
+	smartAlbumBadgeLimit = 20
+	smartAlbumBadgeTTL   = time.Minute
+)
+
+// badgeKey 相册修改或用户重新查看后键随之变化，旧的缓存不再命中
+type badgeKey struct {
+	albumID   uint
+	userID    uint
+	role      string
+	updatedAt int64
+	since     int64
+}
+
+type badgeEntry struct {
+	count   int64
+	expires time.Time
+}
+
+// badgeCache 角标新增数量的短期缓存
+type badgeCache struct {
+	mu      sync.Mutex
+	entries map[badgeKey]badgeEntry
+}
+
+// 清理过期缓存的阈值，避免缓存无限增长
+const badgeCachePruneSize = 1024
+
+var smartAlbumBadges = &badgeCache{entries: make(map[badgeKey]badgeEntry)}
+
+func (b *badgeCache) get(key badgeKey, now time.Time) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return 0, false
+	}
+	return entry.count, true
+}
+
+func (b *badgeCache) set(key badgeKey, count int64, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) >= badgeCachePruneSize {
+		for k, entry := range b.entries {
+			if !now.Before(entry.expires) {
+				delete(b.entries, k)
+			}
+		}
+	}
+	b.entries[key] = badgeEntry{count: count, expires: now.Add(smartAlbumBadgeTTL)}
+}
+
+type smartAlbumRequest struct {
+	Name             string            `json:"name"`
+	Description      *string           `json:"description"`
+	Params           map[string]string `json:"params"`
+	ShowBadge        *bool             `json:"show_badge"`
+	SharedUserIDs    *[]uint           `json:"shared_user_ids"`
+	SharedWorkflowID *uint             `json:"shared_workflow_id"` // 为 0 表示取消共享
+}
+
+// filterSearchParams 只保留素材筛选参数并校验
+func filterSearchParams(raw map[string]string) (url.Values, error) {
+	params := url.Values{}
+	for _, key := range materials.SearchMaterialFilterParams {
+		if v, ok := raw[key]; ok && v != "" {
+			params.Set(key, v)
+		}
+	}
+	return params, materials.ValidateSearchParams(params)
+}
+
+func encodeParams(params url.Values) string {
+	flat := make(map[string]string, len(params))
+	for key := range params {
+		flat[key] = params.Get(key)
+	}
+	data, _ := json.Marshal(flat)
+	return string(data)
+}
+
+// isWorkflowParticipant 用户是否为工作流的创建者或成员
+func (s *AlbumService) isWorkflowParticipant(workflowID, userID uint) bool {
+	var count int64
+	s.db.Model(&models.WorkflowGroup{}).
+		Where("id = ? AND (created_by = ? OR id IN (SELECT workflow_id FROM workflow_members WHERE user_id = ?))", workflowID, userID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// canShareToWorkflow 只能共享给自己创建或参与的工作流
+func (s *AlbumService) canShareToWorkflow(workflowID, userID uint, role string) bool {
+	return role == "admin" || s.isWorkflowParticipant(workflowID, userID)
+}
+
+// validateShareUsers 检查共享用户是否都存在
+func (s *AlbumService) validateShareUsers(userIDs []uint) bool {
+	userIDs = uniqueIDs(userIDs)
+	if len(userIDs) == 0 {
+		return true
+	}
+	var count int64
+	s.db.Model(&models.User{}).Where("id IN ?", userIDs).Count(&count)
+	return int(count) == len(userIDs)
+}
+
+// canViewSmartAlbum 创建者、管理员、被共享的用户以及共享工作流的成员可以查看
+func (s *AlbumService) canViewSmartAlbum(album *models.SmartAlbum, userID uint, role string) bool {
+	if album.CreatedBy == userID || role == "admin" {
+		return true
+	}
+	for _, share := range album.Shares {
+		if share.UserID == userID {
+			return true
+		}
+	}
+	return album.SharedWorkflowID != nil && s.isWorkflowParticipant(*album.SharedWorkflowID, userID)
+}
+
+// getSmartAlbum 按路径参数获取智能相册，失败时写入错误响应
+func (s *AlbumService) getSmartAlbum(c *gin.Context) (*models.SmartAlbum, bool) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的相册ID"})
+		return nil, false
+	}
+	var album models.SmartAlbum
+	if err := s.db.Preload("Creator").Preload("Shares").First(&album, albumID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "智能相册不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取智能相册失败"})
+		return nil, false
+	}
+	return &album, true
+}
+
+// smartAlbumResponse 转换为响应格式，开启角标时附带新增数量
+func (s *AlbumService) smartAlbumResponse(album *models.SmartAlbum, userID uint, role string) *models.SmartAlbumResponse {
+	response := album.ToSmartAlbumResponse()
+	if !album.ShowBadge {
+		return response
+	}
+	var since *time.Time
+	var view models.SmartAlbumView
+	if err := s.db.Where("album_id = ? AND user_id = ?", album.ID, userID).First(&view).Error; err == nil {
+		since = &view.LastViewedAt
+	}
+	response.NewCount = s.badgeCount(album, userID, role, since)
+	return response
+}
+
+// badgeCount 计算自 since 以来新增的匹配数量，优先使用缓存，失败时返回 nil
+func (s *AlbumService) badgeCount(album *models.SmartAlbum, userID uint, role string, since *time.Time) *int64 {
+	now := time.Now()
+	key := badgeKey{albumID: album.ID, userID: userID, role: role, updatedAt: album.UpdatedAt.UnixNano()}
+	if since != nil {
+		key.since = since.UnixNano()
+	}
+	if count, ok := smartAlbumBadges.get(key, now); ok {
+		return &count
+	}
+
+	params := url.Values{}
+	for key, value := range album.ParamMap() {
+		params.Set(key, value)
+	}
+	count, err := materials.CountSearchMatches(params, userID, role, since)
+	if err != nil {
+		return nil
+	}
+	smartAlbumBadges.set(key, count, now)
+	return &count
+}
+
+// replaceShares 重新设置共享用户
+func replaceShares(tx *gorm.DB, albumID uint, userIDs []uint) error {
+	if err := tx.Where("album_id = ?", albumID).Delete(&models.SmartAlbumShare{}).Error; err != nil {
+		return err
+	}
+	seen := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		if err := tx.Create(&models.SmartAlbumShare{AlbumID: albumID, UserID: userID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSmartAlbums 获取自己创建及共享给自己的智能相册
+func GetSmartAlbums(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	uid := userID.(uint)
+
+	var albums []models.SmartAlbum
+	err := service.db.Preload("Creator").Preload("Shares").
+		Where("created_by = ?", uid).
+		Or("id IN (SELECT album_id FROM smart_album_shares WHERE user_id = ?)", uid).
+		Or("shared_workflow_id IN (SELECT id FROM workflow_groups WHERE created_by = ?)", uid).
+		Or("shared_workflow_id IN (SELECT workflow_id FROM workflow_members WHERE user_id = ?)", uid).
+		Order("updated_at DESC").Find(&albums).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取智能相册列表失败"})
+		return
+	}
+
+	// 一次查出所有查看记录
+	albumIDs := make([]uint, 0, len(albums))
+	for _, album := range albums {
+		albumIDs = append(albumIDs, album.ID)
+	}
+	lastViewed := make(map[uint]time.Time)
+	if len(albumIDs) > 0 {
+		var views []models.SmartAlbumView
+		service.db.Where("user_id = ? AND album_id IN ?", uid, albumIDs).Find(&views)
+		for _, view := range views {
+			lastViewed[view.AlbumID] = view.LastViewedAt
+		}
+	}
+
+	responses := []models.SmartAlbumResponse{}
+	badges := 0
+	for i := range albums {
+		response := albums[i].ToSmartAlbumResponse()
+		if albums[i].ShowBadge && badges < smartAlbumBadgeLimit {
+			badges++
+			var since *time.Time
+			if viewedAt, ok := lastViewed[albums[i].ID]; ok {
+				since = &viewedAt
+			}
+			response.NewCount = service.badgeCount(&albums[i], uid, userRole.(string), since)
+		}
+		responses = append(responses, *response)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// CreateSmartAlbum 将一组搜索参数保存为智能相册
+func CreateSmartAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	var req smartAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "相册名称不能为空"})
+		return
+	}
+	params, err := filterSearchParams(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SharedWorkflowID != nil && *req.SharedWorkflowID != 0 &&
+		!service.canShareToWorkflow(*req.SharedWorkflowID, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能共享给自己参与的工作流"})
+		return
+	}
+	if req.SharedUserIDs != nil && !service.validateShareUsers(*req.SharedUserIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "共享用户不存在"})
+		return
+	}
+
+	album := models.SmartAlbum{
+		Name:      req.Name,
+		Params:    encodeParams(params),
+		CreatedBy: userID.(uint),
+	}
+	if req.Description != nil {
+		album.Description = *req.Description
+	}
+	if req.ShowBadge != nil {
+		album.ShowBadge = *req.ShowBadge
+	}
+	if req.SharedWorkflowID != nil && *req.SharedWorkflowID != 0 {
+		album.SharedWorkflowID = req.SharedWorkflowID
+	}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&album).Error; err != nil {
+			return err
+		}
+		if req.SharedUserIDs != nil {
+			return replaceShares(tx, album.ID, *req.SharedUserIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建智能相册失败"})
+		return
+	}
+
+	service.db.Preload("Creator").Preload("Shares").First(&album, album.ID)
+	c.JSON(http.StatusCreated, service.smartAlbumResponse(&album, userID.(uint), userRole.(string)))
+}
+
+// GetSmartAlbum 获取智能相册详情
+func GetSmartAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getSmartAlbum(c)
+	if !ok {
+		return
+	}
+	if !service.canViewSmartAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看此智能相册"})
+		return
+	}
+	c.JSON(http.StatusOK, service.smartAlbumResponse(album, userID.(uint), userRole.(string)))
+}
+
+// UpdateSmartAlbum 更新智能相册（仅创建者或管理员）
+func UpdateSmartAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getSmartAlbum(c)
+	if !ok {
+		return
+	}
+	if album.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限修改此智能相册"})
+		return
+	}
+
+	var req smartAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.SharedUserIDs != nil && !service.validateShareUsers(*req.SharedUserIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "共享用户不存在"})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ShowBadge != nil {
+		updates["show_badge"] = *req.ShowBadge
+	}
+	if req.Params != nil {
+		params, err := filterSearchParams(req.Params)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["params"] = encodeParams(params)
+	}
+	if req.SharedWorkflowID != nil {
+		if *req.SharedWorkflowID == 0 {
+			updates["shared_workflow_id"] = nil
+		} else if service.canShareToWorkflow(*req.SharedWorkflowID, userID.(uint), userRole.(string)) {
+			updates["shared_workflow_id"] = *req.SharedWorkflowID
+		} else {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能共享给自己参与的工作流"})
+			return
+		}
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(album).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.SharedUserIDs != nil {
+			return replaceShares(tx, album.ID, *req.SharedUserIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新智能相册失败"})
+		return
+	}
+
+	service.db.Preload("Creator").Preload("Shares").First(album, album.ID)
+	c.JSON(http.StatusOK, service.smartAlbumResponse(album, userID.(uint), userRole.(string)))
+}
+
+// DeleteSmartAlbum 删除智能相册（仅创建者或管理员）
+func DeleteSmartAlbum(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getSmartAlbum(c)
+	if !ok {
+		return
+	}
+	if album.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限删除此智能相册"})
+		return
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.SmartAlbumShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.SmartAlbumView{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(album).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除智能相册失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "智能相册删除成功"})
+}
+
+// GetSmartAlbumMaterials 实时计算智能相册中的素材，并记录查看时间
+func GetSmartAlbumMaterials(c *gin.Context) {
+	service := NewAlbumService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	album, ok := service.getSmartAlbum(c)
+	if !ok {
+		return
+	}
+	if !service.canViewSmartAlbum(album, userID.(uint), userRole.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看此智能相册"})
+		return
+	}
+
+	// 保存的筛选参数 + 查看者指定的分页与排序参数
+	params := url.Values{}
+	for key, value := range album.ParamMap() {
+		params.Set(key, value)
+	}
+	query := c.Request.URL.Query()
+	for _, key := range smartAlbumViewParams {
+		if query.Has(key) {
+			params.Set(key, query.Get(key))
+		}
+	}
+
+	service.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "album_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_viewed_at"}),
+	}).Create(&models.SmartAlbumView{AlbumID: album.ID, UserID: userID.(uint), LastViewedAt: time.Now()})
+
+	materials.SearchMaterialsWithParams(c, params)
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"ahsfnu-media-cloud/internal/database"
 	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
 	"ahsfnu-media-cloud/internal/utils"
 	"net/http"
 
@@ -64,6 +65,10 @@ func UpdateProfile(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
 		return
 	}
+	// 用户名变化后同步其素材的检索索引
+	if req.Username != "" {
+		services.ReindexMaterialsAsync(db, "uploaded_by = ?", user.ID)
+	}
 	c.JSON(http.StatusOK, user)
 }
 
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"log"
 	"net/http"
 
 	"ahsfnu-media-cloud/internal/database"
@@ -142,6 +143,17 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 通知邀请码的生成者
+	err = services.Notify(db, &models.Notification{
+		UserID:  invite.CreatedBy,
+		Type:    models.NotificationInviteUsed,
+		Title:   "邀请码 " + invite.Code + " 已被 " + user.Username + " 使用",
+		ActorID: &user.ID,
+	})
+	if err != nil {
+		log.Printf("发送邀请码使用通知失败: %v", err)
+	}
+
 	// 生成JWT token
 	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
 	if err != nil {
@@ -0,0 +1,309 @@
+package embed
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EmbedService struct {
+	db *gorm.DB
+}
+
+func NewEmbedService() *EmbedService {
+	return &EmbedService{
+		db: database.GetDB(),
+	}
+}
+
+const (
+	// 未知尺寸时的默认嵌入尺寸
+	defaultEmbedWidth  = 640
+	defaultEmbedHeight = 360
+	// oEmbed 响应建议的缓存时间（秒）
+	oembedCacheAge = 3600
+	// 缩略图宽度：图片缩略图为正方形，视频缩略图按原比例
+	thumbnailWidth = 200
+)
+
+// 可被 oEmbed 解析的素材地址路径：嵌入页、素材详情页或画廊详情页
+var embeddableURLPattern = regexp.MustCompile(`/(?:embed|materials)/(\d+)/?$`)
+
+// OEmbedResponse oEmbed 1.0 响应
+type OEmbedResponse struct {
+	Type            string `json:"type"` // photo 或 video
+	Version         string `json:"version"`
+	Title           string `json:"title,omitempty"`
+	AuthorName      string `json:"author_name,omitempty"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	URL             string `json:"url,omitempty"`
+	HTML            string `json:"html,omitempty"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+}
+
+// errNoBaseURL 未配置对外访问地址时的错误信息
+const errNoBaseURL = "服务器未配置对外访问地址 PUBLIC_BASE_URL"
+
+// publicBaseURL 对外访问地址，未配置时返回空字符串。
+// 嵌入页与 oEmbed 响应会被共享缓存，地址不能取自客户端可控的 Host 或 X-Forwarded-* 请求头
+func publicBaseURL() string {
+	return config.AppConfig.Embed.PublicBaseURL
+}
+
+// absoluteURL 将站内路径转换为绝对地址
+func absoluteURL(base, path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return base + path
+}
+
+// linkURL 嵌入内容的回链地址
+func linkURL(base string, materialID uint) string {
+	id := strconv.FormatUint(uint64(materialID), 10)
+	if pattern := config.AppConfig.Embed.LinkURL; pattern != "" {
+		return strings.ReplaceAll(pattern, "{id}", id)
+	}
+	return base + "/materials/" + id
+}
+
+// embeddableMaterialID 从素材地址中解析素材ID，地址的主机须与对外访问地址一致
+func embeddableMaterialID(target *url.URL, base string) (uint64, bool) {
+	baseURL, err := url.Parse(base)
+	if err != nil || baseURL.Host == "" || !strings.EqualFold(target.Host, baseURL.Host) {
+		return 0, false
+	}
+	match := embeddableURLPattern.FindStringSubmatch(target.Path)
+	if match == nil {
+		return 0, false
+	}
+	materialID, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return materialID, true
+}
+
+// frameAncestors 按配置生成 CSP frame-ancestors 指令
+func frameAncestors() string {
+	origins := config.AppConfig.Embed.AllowedOrigins
+	if len(origins) == 0 {
+		return "frame-ancestors *"
+	}
+	return "frame-ancestors 'self' " + strings.Join(origins, " ")
+}
+
+// publicMaterial 获取公开素材并转换为公开响应格式（文件地址已填充）
+func (s *EmbedService) publicMaterial(materialID uint64) (*models.PublicMaterialResponse, bool) {
+	var material models.Material
+	err := materials.NewMaterialQueryBuilder(s.db).WithPublic().Build().
+		Where("materials.id = ?", materialID).First(&material).Error
+	if err != nil {
+		return nil, false
+	}
+	response := materials.PublicMaterialResponses([]models.Material{material})[0]
+	return &response, true
+}
+
+// fitSize 按最大宽高等比缩放
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = math.Min(scale, float64(maxWidth)/float64(width))
+	}
+	if maxHeight > 0 && height > maxHeight {
+		scale = math.Min(scale, float64(maxHeight)/float64(height))
+	}
+	return int(math.Round(float64(width) * scale)), int(math.Round(float64(height) * scale))
+}
+
+// materialSize 素材尺寸，未知时使用默认尺寸
+func materialSize(material *models.PublicMaterialResponse) (int, int) {
+	if material.Width != nil && material.Height != nil && *material.Width > 0 && *material.Height > 0 {
+		return *material.Width, *material.Height
+	}
+	return defaultEmbedWidth, defaultEmbedHeight
+}
+
+// thumbnailSize 缩略图尺寸
+func thumbnailSize(material *models.PublicMaterialResponse) (int, int) {
+	if material.FileType == "image" {
+		return thumbnailWidth, thumbnailWidth
+	}
+	width, height := materialSize(material)
+	return thumbnailWidth, int(math.Round(float64(thumbnailWidth) * float64(height) / float64(width)))
+}
+
+// OEmbed oEmbed 提供方接口，只支持公开素材与 JSON 格式
+func OEmbed(c *gin.Context) {
+	service := NewEmbedService()
+
+	base := publicBaseURL()
+	if base == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errNoBaseURL})
+		return
+	}
+	if format := c.DefaultQuery("format", "json"); format != "json" {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "只支持 json 格式"})
+		return
+	}
+	target, err := url.Parse(c.Query("url"))
+	if err != nil || c.Query("url") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 url 参数"})
+		return
+	}
+	materialID, ok := embeddableMaterialID(target, base)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的素材地址"})
+		return
+	}
+	material, ok := service.publicMaterial(materialID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "素材不存在或未公开"})
+		return
+	}
+	maxWidth, _ := strconv.Atoi(c.Query("maxwidth"))
+	maxHeight, _ := strconv.Atoi(c.Query("maxheight"))
+
+	width, height := materialSize(material)
+	width, height = fitSize(width, height, maxWidth, maxHeight)
+	title := material.Caption
+	if title == "" {
+		title = material.OriginalFilename
+	}
+	response := OEmbedResponse{
+		Version:      "1.0",
+		Title:        title,
+		AuthorName:   material.UploaderName,
+		ProviderName: config.AppConfig.Embed.ProviderName,
+		ProviderURL:  base,
+		CacheAge:     oembedCacheAge,
+		Width:        width,
+		Height:       height,
+		ThumbnailURL: absoluteURL(base, material.ThumbnailPath),
+	}
+	if response.ThumbnailURL != "" {
+		response.ThumbnailWidth, response.ThumbnailHeight = thumbnailSize(material)
+	}
+
+	embedURL := fmt.Sprintf("%s/embed/%d", base, material.ID)
+	iframe := fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" frameborder="0" allowfullscreen></iframe>`,
+		template.HTMLEscapeString(embedURL), width, height)
+	switch {
+	case material.FileType == "image" && material.FilePath != "":
+		response.Type = "photo"
+		response.URL = absoluteURL(base, material.FilePath)
+	case material.FileType == "image" && material.ThumbnailPath != "":
+		// 原图无法直接访问时以缩略图代替
+		response.Type = "photo"
+		response.URL = response.ThumbnailURL
+		response.Width, response.Height = response.ThumbnailWidth, response.ThumbnailHeight
+	default:
+		response.Type = "video"
+		response.HTML = iframe
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// embedPageTemplate 嵌入页：自适应宽度的图片或视频播放器，附拍摄者署名与回链
+var embedPageTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Title}}">
+<style>
+html, body { margin: 0; height: 100%; background: #000; font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; }
+.media { position: relative; width: 100%; height: 100%; display: flex; align-items: center; justify-content: center; }
+.media img, .media video { max-width: 100%; max-height: 100%; width: auto; height: auto; display: block; }
+.credit { position: absolute; left: 0; right: 0; bottom: 0; padding: 6px 10px; color: #fff; font-size: 13px;
+  background: linear-gradient(transparent, rgba(0, 0, 0, .6)); display: flex; justify-content: space-between; gap: 8px; }
+.credit a { color: #fff; text-decoration: none; white-space: nowrap; }
+.credit span { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+</style>
+</head>
+<body>
+<div class="media">
+{{- if .IsVideo}}
+<video src="{{.FileURL}}" poster="{{.ThumbnailURL}}" controls playsinline preload="metadata"></video>
+{{- else}}
+<img src="{{.ImageURL}}" alt="{{.Title}}">
+{{- end}}
+<div class="credit">
+<span>{{.Title}}{{if .Author}} · 摄影：{{.Author}}{{end}}</span>
+<a href="{{.LinkURL}}" target="_blank" rel="noopener">{{.ProviderName}} ↗</a>
+</div>
+</div>
+</body>
+</html>
+`))
+
+// EmbedPage 公开素材的嵌入页，按配置限制可嵌入的来源
+func EmbedPage(c *gin.Context) {
+	service := NewEmbedService()
+
+	base := publicBaseURL()
+	if base == "" {
+		c.String(http.StatusServiceUnavailable, errNoBaseURL)
+		return
+	}
+	materialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, "无效的素材ID")
+		return
+	}
+	material, ok := service.publicMaterial(materialID)
+	if !ok {
+		c.String(http.StatusNotFound, "素材不存在或未公开")
+		return
+	}
+
+	title := material.Caption
+	if title == "" {
+		title = material.OriginalFilename
+	}
+	imageURL := material.FilePath
+	if material.FileType != "image" || imageURL == "" {
+		imageURL = material.ThumbnailPath
+	}
+	pageURL := fmt.Sprintf("%s/embed/%d", base, material.ID)
+	data := gin.H{
+		"Title":        title,
+		"Author":       material.UploaderName,
+		"IsVideo":      material.FileType == "video" && material.FilePath != "",
+		"FileURL":      absoluteURL(base, material.FilePath),
+		"ImageURL":     absoluteURL(base, imageURL),
+		"ThumbnailURL": absoluteURL(base, material.ThumbnailPath),
+		"LinkURL":      linkURL(base, material.ID),
+		"ProviderName": config.AppConfig.Embed.ProviderName,
+		"OEmbedURL":    base + "/api/v1/oembed?url=" + url.QueryEscape(pageURL),
+	}
+
+	c.Header("Content-Security-Policy", frameAncestors())
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := embedPageTemplate.Execute(c.Writer, data); err != nil {
+		c.Error(err)
+	}
+}
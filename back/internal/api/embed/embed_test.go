@@ -0,0 +1,34 @@
+package embed
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEmbeddableMaterialID(t *testing.T) {
+	const base = "https://media.example.edu.cn"
+	tests := []struct {
+		url  string
+		id   uint64
+		want bool
+	}{
+		{"https://media.example.edu.cn/embed/12", 12, true},
+		{"https://MEDIA.example.edu.cn/materials/7/", 7, true},
+		{"https://evil.example.com/embed/12", 0, false},
+		{"/embed/12", 0, false},
+		{"https://media.example.edu.cn/albums/12", 0, false},
+		{"https://media.example.edu.cn/embed/99999999999", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, ok := embeddableMaterialID(target, base)
+			if ok != tt.want || id != tt.id {
+				t.Fatalf("得到 %d %v，期望 %d %v", id, ok, tt.id, tt.want)
+			}
+		})
+	}
+}
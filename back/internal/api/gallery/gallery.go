@@ -0,0 +1,250 @@
+package gallery
+
+import (
+	"net/http"
+	"strconv"
+
+	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GalleryService struct {
+	db            *gorm.DB
+	uploadService *services.UploadService
+}
+
+func NewGalleryService() *GalleryService {
+	return &GalleryService{
+		db:            database.GetDB(),
+		uploadService: services.NewUploadService(),
+	}
+}
+
+// 公开画廊的默认排序：上传时间倒序
+const galleryOrder = "materials.upload_time DESC, materials.id DESC"
+
+// GalleryTag 含公开素材数量的标签
+type GalleryTag struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Count int64  `json:"count"`
+}
+
+// GalleryWorkflow 展示中的工作流
+type GalleryWorkflow struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	Color          string `json:"color"`
+	Count          int64  `json:"count"`
+	CoverThumbnail string `json:"cover_thumbnail,omitempty"`
+}
+
+// isLoggedIn 可选认证中间件识别出了登录用户
+func isLoggedIn(c *gin.Context) bool {
+	_, exists := c.Get("user_id")
+	return exists
+}
+
+// pageParams 解析分页参数
+func pageParams(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// materialData 匿名访问者只获得公开信息，登录用户获得完整信息
+func materialData(c *gin.Context, items []models.Material) interface{} {
+	if isLoggedIn(c) {
+		return materials.MaterialResponses(items)
+	}
+	return materials.PublicMaterialResponses(items)
+}
+
+// respondMaterials 分页查询公开素材并写入响应
+func respondMaterials(c *gin.Context, query *gorm.DB, extra gin.H) {
+	page, pageSize := pageParams(c)
+
+	var items []models.Material
+	var total int64
+	query.Count(&total)
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order(galleryOrder).Find(&items).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取素材列表失败"})
+		return
+	}
+
+	response := gin.H{
+		"data": materialData(c, items),
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetGalleryMaterials 浏览公开素材，支持按类型、标签、工作流和关键词筛选
+func GetGalleryMaterials(c *gin.Context) {
+	service := NewGalleryService()
+
+	query := materials.NewMaterialQueryBuilder(service.db).
+		WithPublic().
+		WithFileType(c.Query("file_type")).
+		WithWorkflow(c.Query("workflow_id")).
+		WithTags(c.Query("tags")).
+		WithKeyword(c.Query("keyword")).
+		Build()
+	respondMaterials(c, query, nil)
+}
+
+// GetGalleryMaterial 获取公开素材详情
+func GetGalleryMaterial(c *gin.Context) {
+	service := NewGalleryService()
+
+	materialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的素材ID"})
+		return
+	}
+	var material models.Material
+	err = materials.NewMaterialQueryBuilder(service.db).WithPublic().Build().
+		Where("materials.id = ?", materialID).First(&material).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "素材不存在或未公开"})
+		return
+	}
+
+	items := []models.Material{material}
+	if isLoggedIn(c) {
+		c.JSON(http.StatusOK, gin.H{"data": materials.MaterialResponses(items)[0]})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": materials.PublicMaterialResponses(items)[0]})
+}
+
+// GetGalleryTags 获取带有公开素材的标签及数量
+func GetGalleryTags(c *gin.Context) {
+	service := NewGalleryService()
+
+	tags := []GalleryTag{}
+	err := service.db.Table("tags").
+		Select("tags.id, tags.name, tags.color, COUNT(DISTINCT material_tags.material_id) AS count").
+		Joins("JOIN material_tags ON material_tags.tag_id = tags.id").
+		Joins("JOIN materials ON materials.id = material_tags.material_id AND materials.is_public = ?", true).
+		Group("tags.id, tags.name, tags.color").
+		Order("count DESC, tags.name").
+		Scan(&tags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// GetGalleryTagMaterials 按标签浏览公开素材
+func GetGalleryTagMaterials(c *gin.Context) {
+	service := NewGalleryService()
+
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return
+	}
+	var tag models.Tag
+	if err := service.db.First(&tag, tagID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	query := materials.NewMaterialQueryBuilder(service.db).
+		WithPublic().
+		WithTags(strconv.FormatUint(tagID, 10)).
+		WithFileType(c.Query("file_type")).
+		Build()
+	respondMaterials(c, query, gin.H{
+		"tag": models.PublicTag{ID: tag.ID, Name: tag.Name, Color: tag.Color},
+	})
+}
+
+// galleryWorkflows 查询含公开素材的工作流，附带数量与封面
+func (s *GalleryService) galleryWorkflows(workflowID uint) ([]GalleryWorkflow, error) {
+	workflows := []GalleryWorkflow{}
+	query := s.db.Table("workflow_groups").
+		Select("workflow_groups.id, workflow_groups.name, workflow_groups.description, workflow_groups.color, COUNT(materials.id) AS count").
+		Joins("JOIN materials ON materials.workflow_id = workflow_groups.id AND materials.is_public = ?", true).
+		Group("workflow_groups.id, workflow_groups.name, workflow_groups.description, workflow_groups.color").
+		Order("MAX(materials.upload_time) DESC")
+	if workflowID != 0 {
+		query = query.Where("workflow_groups.id = ?", workflowID)
+	}
+	if err := query.Scan(&workflows).Error; err != nil {
+		return nil, err
+	}
+
+	// 收藏的公开素材优先作为封面
+	for i := range workflows {
+		var cover models.Material
+		err := s.db.Where("workflow_id = ? AND is_public = ? AND thumbnail_path <> ''", workflows[i].ID, true).
+			Order("is_starred DESC, upload_time DESC").First(&cover).Error
+		if err == nil {
+			workflows[i].CoverThumbnail = s.uploadService.GetThumbnailURL(&cover)
+		}
+	}
+	return workflows, nil
+}
+
+// GetGalleryWorkflows 获取展示中的工作流（至少含一个公开素材）
+func GetGalleryWorkflows(c *gin.Context) {
+	service := NewGalleryService()
+
+	workflows, err := service.galleryWorkflows(0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作流列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": workflows})
+}
+
+// GetGalleryWorkflow 工作流展示页：工作流信息及其公开素材
+func GetGalleryWorkflow(c *gin.Context) {
+	service := NewGalleryService()
+
+	workflowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+	workflows, err := service.galleryWorkflows(uint(workflowID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作流失败"})
+		return
+	}
+	if len(workflows) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "工作流不存在或没有公开素材"})
+		return
+	}
+
+	query := materials.NewMaterialQueryBuilder(service.db).
+		WithPublic().
+		WithWorkflow(strconv.FormatUint(workflowID, 10)).
+		WithFileType(c.Query("file_type")).
+		Build()
+	respondMaterials(c, query, gin.H{"workflow": workflows[0]})
+}
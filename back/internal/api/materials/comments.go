@@ -0,0 +1,383 @@
+package materials
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 评论内容的最大长度（字符）
+const commentMaxLength = 5000
+
+type commentRequest struct {
+	Content   *string                  `json:"content"`
+	ParentID  *uint                    `json:"parent_id"`
+	Region    *models.CommentRegion    `json:"region"`
+	TimeRange *models.CommentTimeRange `json:"time_range"`
+	// 修改时清除锚点
+	ClearAnchor bool `json:"clear_anchor"`
+}
+
+// canViewMaterial 素材所有者、管理员与所属工作流成员可以查看，公开素材所有人可以查看
+func canViewMaterial(db *gorm.DB, material *models.Material, userID uint, role string) bool {
+	return services.CanViewMaterial(db, material, userID, role)
+}
+
+// getViewableMaterial 获取当前用户可以查看的素材，失败时写入错误响应
+func getViewableMaterial(c *gin.Context, service *MaterialService) (*models.Material, bool) {
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return nil, false
+	}
+	var material models.Material
+	if err := service.db.First(&material, materialID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			errorResponse(c, http.StatusNotFound, "素材不存在")
+			return nil, false
+		}
+		errorResponse(c, http.StatusInternalServerError, "获取素材失败")
+		return nil, false
+	}
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !canViewMaterial(service.db, &material, userID.(uint), userRole.(string)) {
+		errorResponse(c, http.StatusForbidden, "没有权限查看此素材")
+		return nil, false
+	}
+	return &material, true
+}
+
+// getMaterialComment 获取素材下的评论，失败时写入错误响应
+func getMaterialComment(c *gin.Context, service *MaterialService, material *models.Material) (*models.MaterialComment, bool) {
+	commentID, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "无效的评论ID")
+		return nil, false
+	}
+	var comment models.MaterialComment
+	if err := service.db.Where("material_id = ?", material.ID).First(&comment, commentID).Error; err != nil {
+		errorResponse(c, http.StatusNotFound, "评论不存在")
+		return nil, false
+	}
+	return &comment, true
+}
+
+// validateCommentContent 检查评论内容，返回去除首尾空白后的内容
+func validateCommentContent(content string) (string, string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", "评论内容不能为空"
+	}
+	if len([]rune(content)) > commentMaxLength {
+		return "", "评论内容不能超过 5000 个字符"
+	}
+	return content, ""
+}
+
+// validateCommentAnchor 检查锚点：区域只能用于图片，时间段只能用于视频
+func validateCommentAnchor(material *models.Material, region *models.CommentRegion, timeRange *models.CommentTimeRange) string {
+	if region != nil && timeRange != nil {
+		return "评论只能锚定到区域或时间段之一"
+	}
+	if region != nil {
+		if material.FileType != "image" {
+			return "只有图片可以按区域评论"
+		}
+		if region.X < 0 || region.Y < 0 || region.Width <= 0 || region.Height <= 0 ||
+			region.X+region.Width > 1 || region.Y+region.Height > 1 {
+			return "区域坐标应为 0-1 之间的比例且不能超出图片"
+		}
+	}
+	if timeRange != nil {
+		if material.FileType != "video" {
+			return "只有视频可以按时间段评论"
+		}
+		if timeRange.Start < 0 || timeRange.End < timeRange.Start {
+			return "无效的时间段"
+		}
+		if material.Duration != nil && timeRange.End > float64(*material.Duration) {
+			return "时间段超出视频时长"
+		}
+	}
+	return ""
+}
+
+// notifyMentions 通知评论中新 @ 到的用户，只通知能查看该素材的用户
+func notifyMentions(db *gorm.DB, material *models.Material, comment *models.MaterialComment, previousContent string) {
+	previous := make(map[string]bool)
+	for _, username := range services.ParseMentions(previousContent) {
+		previous[username] = true
+	}
+	var usernames []string
+	for _, username := range services.ParseMentions(comment.Content) {
+		if !previous[username] {
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return
+	}
+
+	var users []models.User
+	if err := db.Select("id, role").Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		log.Printf("查询评论提及的用户失败: %v", err)
+		return
+	}
+	for i := range users {
+		if !canViewMaterial(db, material, users[i].ID, users[i].Role) {
+			continue
+		}
+		err := services.Notify(db, &models.Notification{
+			UserID:     users[i].ID,
+			Type:       models.NotificationMention,
+			Title:      "有人在「" + material.OriginalFilename + "」的评论中提到了你",
+			Content:    comment.Content,
+			ActorID:    &comment.UserID,
+			MaterialID: &material.ID,
+			CommentID:  &comment.ID,
+		})
+		if err != nil {
+			log.Printf("发送提及通知失败: %v", err)
+		}
+	}
+}
+
+// GetMaterialComments 获取素材的评论串，可用 resolved 参数按解决状态筛选
+func GetMaterialComments(c *gin.Context) {
+	service := GetMaterialService()
+
+	material, ok := getViewableMaterial(c, service)
+	if !ok {
+		return
+	}
+
+	query := service.db.Preload("User").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Preload("Replies.User").
+		Where("material_id = ? AND parent_id IS NULL", material.ID)
+	if resolved := c.Query("resolved"); resolved != "" {
+		query = query.Where("resolved = ?", resolved == "true")
+	}
+
+	var comments []models.MaterialComment
+	if err := query.Order("created_at, id").Find(&comments).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取评论失败")
+		return
+	}
+	responses := []models.MaterialCommentResponse{}
+	for i := range comments {
+		responses = append(responses, *comments[i].ToMaterialCommentResponse())
+	}
+	successResponse(c, responses)
+}
+
+// CreateMaterialComment 发表评论或回复，回复统一挂在所属讨论串的顶层评论下
+func CreateMaterialComment(c *gin.Context) {
+	service := GetMaterialService()
+	userID, _ := c.Get("user_id")
+
+	material, ok := getViewableMaterial(c, service)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+	if req.Content == nil {
+		errorResponse(c, http.StatusBadRequest, "评论内容不能为空")
+		return
+	}
+	content, message := validateCommentContent(*req.Content)
+	if message != "" {
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	comment := models.MaterialComment{
+		MaterialID: material.ID,
+		UserID:     userID.(uint),
+		Content:    content,
+	}
+	if req.ParentID != nil {
+		var parent models.MaterialComment
+		if err := service.db.Where("material_id = ?", material.ID).First(&parent, *req.ParentID).Error; err != nil {
+			errorResponse(c, http.StatusBadRequest, "回复的评论不存在")
+			return
+		}
+		rootID := parent.ID
+		if parent.ParentID != nil {
+			rootID = *parent.ParentID
+		}
+		comment.ParentID = &rootID
+		if req.Region != nil || req.TimeRange != nil {
+			errorResponse(c, http.StatusBadRequest, "回复不能锚定区域或时间段")
+			return
+		}
+	} else {
+		if message := validateCommentAnchor(material, req.Region, req.TimeRange); message != "" {
+			errorResponse(c, http.StatusBadRequest, message)
+			return
+		}
+		comment.SetRegion(req.Region)
+		comment.SetTimeRange(req.TimeRange)
+	}
+
+	if err := service.db.Create(&comment).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "发表评论失败")
+		return
+	}
+	notifyMentions(service.db, material, &comment, "")
+	service.db.Preload("User").First(&comment, comment.ID)
+	c.JSON(http.StatusCreated, gin.H{"data": comment.ToMaterialCommentResponse()})
+}
+
+// UpdateMaterialComment 修改评论内容或锚点（仅评论作者）
+func UpdateMaterialComment(c *gin.Context) {
+	service := GetMaterialService()
+	userID, _ := c.Get("user_id")
+
+	material, ok := getViewableMaterial(c, service)
+	if !ok {
+		return
+	}
+	comment, ok := getMaterialComment(c, service, material)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID.(uint) {
+		errorResponse(c, http.StatusForbidden, "只能修改自己的评论")
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	previousContent := comment.Content
+	if req.Content != nil {
+		content, message := validateCommentContent(*req.Content)
+		if message != "" {
+			errorResponse(c, http.StatusBadRequest, message)
+			return
+		}
+		comment.Content = content
+	}
+	if req.ClearAnchor {
+		comment.SetRegion(nil)
+		comment.SetTimeRange(nil)
+	} else if req.Region != nil || req.TimeRange != nil {
+		if comment.ParentID != nil {
+			errorResponse(c, http.StatusBadRequest, "回复不能锚定区域或时间段")
+			return
+		}
+		if message := validateCommentAnchor(material, req.Region, req.TimeRange); message != "" {
+			errorResponse(c, http.StatusBadRequest, message)
+			return
+		}
+		comment.SetRegion(req.Region)
+		comment.SetTimeRange(req.TimeRange)
+	}
+	now := time.Now()
+	comment.EditedAt = &now
+
+	err := service.db.Model(comment).Select("content", "region_x", "region_y", "region_width", "region_height",
+		"time_start", "time_end", "edited_at").Updates(comment).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "修改评论失败")
+		return
+	}
+	notifyMentions(service.db, material, comment, previousContent)
+	service.db.Preload("User").First(comment, comment.ID)
+	successResponse(c, comment.ToMaterialCommentResponse())
+}
+
+// DeleteMaterialComment 删除评论（评论作者、素材所有者或管理员），删除顶层评论时一并删除其回复
+func DeleteMaterialComment(c *gin.Context) {
+	service := GetMaterialService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	material, ok := getViewableMaterial(c, service)
+	if !ok {
+		return
+	}
+	comment, ok := getMaterialComment(c, service, material)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID.(uint) && material.UploadedBy != userID.(uint) && userRole.(string) != "admin" {
+		errorResponse(c, http.StatusForbidden, "没有权限删除此评论")
+		return
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("parent_id = ?", comment.ID).Delete(&models.MaterialComment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(comment).Error
+	})
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除评论失败")
+		return
+	}
+	successResponse(c, gin.H{"message": "评论删除成功"})
+}
+
+// setCommentResolved 标记讨论串已解决或重新打开（评论作者、素材所有者或管理员）
+func setCommentResolved(c *gin.Context, resolved bool) {
+	service := GetMaterialService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	material, ok := getViewableMaterial(c, service)
+	if !ok {
+		return
+	}
+	comment, ok := getMaterialComment(c, service, material)
+	if !ok {
+		return
+	}
+	if comment.ParentID != nil {
+		errorResponse(c, http.StatusBadRequest, "只能解决顶层评论")
+		return
+	}
+	if comment.UserID != userID.(uint) && material.UploadedBy != userID.(uint) && userRole.(string) != "admin" {
+		errorResponse(c, http.StatusForbidden, "没有权限修改此评论的状态")
+		return
+	}
+
+	updates := map[string]interface{}{"resolved": resolved, "resolved_by": nil, "resolved_at": nil}
+	if resolved {
+		updates["resolved_by"] = userID.(uint)
+		updates["resolved_at"] = time.Now()
+	}
+	if err := service.db.Model(comment).Updates(updates).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "修改评论状态失败")
+		return
+	}
+	service.db.Preload("User").First(comment, comment.ID)
+	successResponse(c, comment.ToMaterialCommentResponse())
+}
+
+// ResolveMaterialComment 将讨论串标记为已解决
+func ResolveMaterialComment(c *gin.Context) {
+	setCommentResolved(c, true)
+}
+
+// UnresolveMaterialComment 重新打开已解决的讨论串
+func UnresolveMaterialComment(c *gin.Context) {
+	setCommentResolved(c, false)
+}
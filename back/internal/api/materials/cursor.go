@@ -0,0 +1,102 @@
+package materials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// materialCursor 游标分页的位置：上一页最后一条素材的排序值与 ID
+type materialCursor struct {
+	SortBy    string          `json:"s"`
+	SortOrder string          `json:"o"`
+	Value     json.RawMessage `json:"v"`
+	ID        uint            `json:"id"`
+}
+
+// encodeMaterialCursor 以最后一条素材生成下一页游标
+func encodeMaterialCursor(sortBy, sortOrder string, last *models.Material) string {
+	value, _ := json.Marshal(materialSortKeys[sortBy].value(last))
+	data, _ := json.Marshal(materialCursor{SortBy: sortBy, SortOrder: sortOrder, Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeMaterialCursor 解析游标
+func decodeMaterialCursor(s string) (*materialCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor materialCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if _, ok := materialSortKeys[cursor.SortBy]; !ok {
+		return nil, errors.New("未知的排序字段")
+	}
+	if cursor.SortOrder != "asc" && cursor.SortOrder != "desc" {
+		return nil, errors.New("未知的排序方向")
+	}
+	if _, err := cursor.sortValue(); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// sortValue 按排序字段类型还原游标中的排序值，NULL 返回 nil
+func (cur *materialCursor) sortValue() (interface{}, error) {
+	if len(cur.Value) == 0 || string(cur.Value) == "null" {
+		return nil, nil
+	}
+	var err error
+	switch materialSortKeys[cur.SortBy].kind {
+	case "time":
+		var v time.Time
+		err = json.Unmarshal(cur.Value, &v)
+		return v, err
+	case "int":
+		var v int64
+		err = json.Unmarshal(cur.Value, &v)
+		return v, err
+	case "float":
+		var v float64
+		err = json.Unmarshal(cur.Value, &v)
+		return v, err
+	case "bool":
+		var v bool
+		err = json.Unmarshal(cur.Value, &v)
+		return v, err
+	default:
+		var v string
+		err = json.Unmarshal(cur.Value, &v)
+		return v, err
+	}
+}
+
+// applyMaterialCursor 添加“位于游标之后”的条件，与 materialOrder 的排序规则
+// （排序字段、NULLS LAST、ID 倒序）保持一致
+func applyMaterialCursor(query *gorm.DB, cursor *materialCursor) *gorm.DB {
+	key := materialSortKeys[cursor.SortBy]
+	value, _ := cursor.sortValue()
+
+	if value == nil {
+		// 已进入排在最后的 NULL 部分
+		return query.Where(key.expr+" IS NULL AND materials.id < ?", cursor.ID)
+	}
+
+	cmp := "<"
+	if cursor.SortOrder == "asc" {
+		cmp = ">"
+	}
+	cond := "(" + key.expr + " " + cmp + " ? OR (" + key.expr + " = ? AND materials.id < ?)"
+	if key.nullable {
+		cond += " OR " + key.expr + " IS NULL"
+	}
+	cond += ")"
+	return query.Where(cond, value, value, cursor.ID)
+}
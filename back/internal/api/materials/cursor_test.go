@@ -0,0 +1,120 @@
+package materials
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestMaterialCursorRoundTrip(t *testing.T) {
+	uploaded := time.Date(2024, 9, 1, 8, 30, 0, 123456789, time.UTC)
+	taken := time.Date(2024, 8, 31, 20, 0, 0, 0, time.UTC)
+	duration := 95
+	sharpness := 132.75
+	material := &models.Material{
+		ID:               42,
+		UploadTime:       uploaded,
+		TakenAt:          &taken,
+		FileSize:         1 << 40,
+		OriginalFilename: "迎新晚会.jpg",
+		Duration:         &duration,
+		IsStarred:        true,
+		SharpnessScore:   &sharpness,
+	}
+	tests := []struct {
+		sortBy string
+		want   interface{}
+	}{
+		{"upload_time", uploaded},
+		{"taken", taken},
+		{"size", int64(1 << 40)},
+		{"filename", "迎新晚会.jpg"},
+		{"duration", int64(95)},
+		{"starred", true},
+		{"sharpness", 132.75},
+		{"exposure", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			cursor, err := decodeMaterialCursor(encodeMaterialCursor(tt.sortBy, "asc", material))
+			if err != nil {
+				t.Fatalf("解析游标返回错误: %v", err)
+			}
+			if cursor.SortBy != tt.sortBy || cursor.SortOrder != "asc" || cursor.ID != 42 {
+				t.Fatalf("游标为 %+v", cursor)
+			}
+			value, err := cursor.sortValue()
+			if err != nil {
+				t.Fatalf("还原排序值返回错误: %v", err)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				if got, ok := value.(time.Time); !ok || !got.Equal(want) {
+					t.Fatalf("排序值为 %v，期望 %v", value, want)
+				}
+				return
+			}
+			if value != tt.want {
+				t.Fatalf("排序值为 %#v，期望 %#v", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMaterialCursorRejects(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"不是 base64", "not*base64"},
+		{"不是 JSON", encode("abc")},
+		{"未知的排序字段", encode(`{"s":"rank","o":"desc","v":1,"id":1}`)},
+		{"未知的排序方向", encode(`{"s":"size","o":"up","v":1,"id":1}`)},
+		{"排序值类型错误", encode(`{"s":"size","o":"desc","v":"big","id":1}`)},
+		{"时间格式错误", encode(`{"s":"taken","o":"desc","v":"2024-09","id":1}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeMaterialCursor(tt.cursor); err == nil {
+				t.Fatal("应返回错误")
+			}
+		})
+	}
+}
+
+func TestApplyMaterialCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		want   string
+	}{
+		{"倒序", `{"s":"size","o":"desc","v":100,"id":7}`,
+			"(materials.file_size < 100 OR (materials.file_size = 100 AND materials.id < 7))"},
+		{"正序", `{"s":"size","o":"asc","v":100,"id":7}`,
+			"(materials.file_size > 100 OR (materials.file_size = 100 AND materials.id < 7))"},
+		{"可为空的字段", `{"s":"duration","o":"desc","v":30,"id":7}`,
+			"(materials.duration < 30 OR (materials.duration = 30 AND materials.id < 7) OR materials.duration IS NULL)"},
+		{"已进入 NULL 部分", `{"s":"duration","o":"desc","v":null,"id":7}`,
+			"materials.duration IS NULL AND materials.id < 7"},
+	}
+	db := dryRunDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeMaterialCursor(base64.RawURLEncoding.EncodeToString([]byte(tt.cursor)))
+			if err != nil {
+				t.Fatalf("解析游标返回错误: %v", err)
+			}
+			sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return applyMaterialCursor(tx.Model(&models.Material{}), cursor).Find(&[]models.Material{})
+			})
+			if !strings.Contains(sql, tt.want) {
+				t.Fatalf("SQL 为 %s，应包含 %s", sql, tt.want)
+			}
+		})
+	}
+}
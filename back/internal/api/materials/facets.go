@@ -0,0 +1,81 @@
+package materials
+
+import (
+	"fmt"
+	"net/url"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 标签、上传者、工作流分面最多返回的分组数
+const facetLimit = 20
+
+// FacetBucket 分面中的一个分组
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// facetQueries 各分面的统计方式，ids 为满足当前筛选条件的素材ID子查询
+var facetQueries = map[string]func(db, ids *gorm.DB) *gorm.DB{
+	"type": func(db, ids *gorm.DB) *gorm.DB {
+		return db.Model(&models.Material{}).
+			Select("materials.file_type AS value, COUNT(*) AS count").
+			Where("materials.id IN (?)", ids).
+			Group("materials.file_type").Order("count DESC")
+	},
+	"tag": func(db, ids *gorm.DB) *gorm.DB {
+		return db.Table("material_tags").
+			Select("CAST(tags.id AS TEXT) AS value, tags.name AS label, COUNT(DISTINCT material_tags.material_id) AS count").
+			Joins("JOIN tags ON tags.id = material_tags.tag_id").
+			Where("material_tags.material_id IN (?)", ids).
+			Group("tags.id, tags.name").Order("count DESC, tags.id").Limit(facetLimit)
+	},
+	"uploader": func(db, ids *gorm.DB) *gorm.DB {
+		return db.Model(&models.Material{}).
+			Select("CAST(users.id AS TEXT) AS value, users.username AS label, COUNT(*) AS count").
+			Joins("JOIN users ON users.id = materials.uploaded_by").
+			Where("materials.id IN (?)", ids).
+			Group("users.id, users.username").Order("count DESC, users.id").Limit(facetLimit)
+	},
+	"workflow": func(db, ids *gorm.DB) *gorm.DB {
+		return db.Model(&models.Material{}).
+			Select("CAST(workflow_groups.id AS TEXT) AS value, workflow_groups.name AS label, COUNT(*) AS count").
+			Joins("JOIN workflow_groups ON workflow_groups.id = materials.workflow_id").
+			Where("materials.id IN (?)", ids).
+			Group("workflow_groups.id, workflow_groups.name").Order("count DESC, workflow_groups.id").Limit(facetLimit)
+	},
+	"month": func(db, ids *gorm.DB) *gorm.DB {
+		return db.Model(&models.Material{}).
+			Select("TO_CHAR("+localTimeExpr("materials.upload_time")+", 'YYYY-MM') AS value, COUNT(*) AS count").
+			Where("materials.id IN (?)", ids).
+			Group("value").Order("value DESC")
+	},
+}
+
+// computeFacets 按与搜索相同的筛选条件与可见性规则统计分面
+func computeFacets(db *gorm.DB, params url.Values, names []string, userID uint, role string) (map[string][]FacetBucket, error) {
+	for _, name := range names {
+		if _, ok := facetQueries[name]; !ok {
+			return nil, fmt.Errorf("未知的分面 %s，可选 type、tag、uploader、workflow、month", name)
+		}
+	}
+
+	facets := make(map[string][]FacetBucket, len(names))
+	for _, name := range names {
+		queryBuilder, _, err := buildSearchQuery(db, params, userID, role)
+		if err != nil {
+			return nil, err
+		}
+		ids := queryBuilder.Build().Select("materials.id")
+		buckets := []FacetBucket{}
+		if err := facetQueries[name](db, ids).Scan(&buckets).Error; err != nil {
+			return nil, err
+		}
+		facets[name] = buckets
+	}
+	return facets, nil
+}
@@ -0,0 +1,187 @@
+package materials
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// GeoJSON 接口默认与最多返回的素材数
+	geoJSONDefaultLimit = 1000
+	geoJSONMaxLimit     = 5000
+	// 按半径检索时允许的最大半径（米）
+	maxSearchRadius = 100000.0
+)
+
+// parseFloats 解析逗号分隔的 n 个浮点数
+func parseFloats(value string, n int) ([]float64, error) {
+	parts := SplitAndTrim(value, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("应为 %d 个逗号分隔的数字", n)
+	}
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数字 %s", part)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// WithLocationVisibleTo 只保留拍摄位置对当前用户可见的素材：
+// 管理员与上传者总是可见，其他人仅在素材未启用去除敏感元数据时可见
+func (qb *MaterialQueryBuilder) WithLocationVisibleTo(userID uint, role string) *MaterialQueryBuilder {
+	qb.query = qb.query.Where("materials.latitude IS NOT NULL AND materials.longitude IS NOT NULL")
+	if role != "admin" {
+		qb.query = qb.query.Where(`(materials.uploaded_by = ? OR NOT COALESCE(materials.strip_metadata,
+			(SELECT w.strip_metadata FROM workflow_groups w WHERE w.id = materials.workflow_id), FALSE))`, userID)
+	}
+	return qb
+}
+
+// WithBoundingBox 按矩形范围筛选，bbox 格式为 最小经度,最小纬度,最大经度,最大纬度
+func (qb *MaterialQueryBuilder) WithBoundingBox(bbox string) error {
+	if bbox == "" {
+		return nil
+	}
+	v, err := parseFloats(bbox, 4)
+	if err != nil {
+		return fmt.Errorf("无效的 bbox: %v", err)
+	}
+	if v[0] > v[2] || v[1] > v[3] {
+		return fmt.Errorf("无效的 bbox: 最小值不能大于最大值")
+	}
+	qb.query = qb.query.Where("materials.longitude BETWEEN ? AND ? AND materials.latitude BETWEEN ? AND ?", v[0], v[2], v[1], v[3])
+	return nil
+}
+
+// WithRadius 按到中心点的距离筛选，near 格式为 纬度,经度，radius 单位为米
+func (qb *MaterialQueryBuilder) WithRadius(near, radius string) error {
+	if near == "" {
+		return nil
+	}
+	v, err := parseFloats(near, 2)
+	if err != nil {
+		return fmt.Errorf("无效的 near: %v", err)
+	}
+	lat, lng := v[0], v[1]
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return fmt.Errorf("无效的 near: 坐标超出范围")
+	}
+	r, err := strconv.ParseFloat(radius, 64)
+	if err != nil || r <= 0 || r > maxSearchRadius {
+		return fmt.Errorf("radius 应为 0 到 %g 之间的米数", maxSearchRadius)
+	}
+	// 先用外接矩形预筛选以利用索引，再精确计算距离
+	minLat, minLng, maxLat, maxLng := services.RadiusBoundingBox(lat, lng, r)
+	qb.query = qb.query.
+		Where("materials.latitude BETWEEN ? AND ? AND materials.longitude BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng).
+		Where(services.HaversineSQL("materials.latitude", "materials.longitude")+" <= ?", lat, lat, lng, r)
+	return nil
+}
+
+// WithPlace 筛选位于命名地点内的素材
+func (qb *MaterialQueryBuilder) WithPlace(placeID string) error {
+	if placeID == "" {
+		return nil
+	}
+	id, err := strconv.ParseUint(placeID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("无效的地点ID")
+	}
+	qb.query = qb.query.Where("EXISTS (SELECT 1 FROM material_places mp WHERE mp.material_id = materials.id AND mp.place_id = ?)", uint(id))
+	return nil
+}
+
+// withGeoFilters 应用地理筛选参数，存在任一地理筛选时只保留位置可见的素材
+func (qb *MaterialQueryBuilder) withGeoFilters(bbox, near, radius, placeID string, userID uint, role string) error {
+	if err := qb.WithBoundingBox(bbox); err != nil {
+		return err
+	}
+	if err := qb.WithRadius(near, radius); err != nil {
+		return err
+	}
+	if err := qb.WithPlace(placeID); err != nil {
+		return err
+	}
+	if bbox != "" || near != "" || placeID != "" {
+		qb.WithLocationVisibleTo(userID, role)
+	}
+	return nil
+}
+
+type geoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         uint                   `json:"id"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GetMaterialsGeoJSON 以 GeoJSON FeatureCollection 返回可见的带拍摄位置的素材，支持与搜索素材相同的筛选参数
+func GetMaterialsGeoJSON(c *gin.Context) {
+	service := GetMaterialService()
+	params := c.Request.URL.Query()
+
+	limit := geoJSONDefaultLimit
+	if params.Has("limit") {
+		var err error
+		if limit, err = strconv.Atoi(params.Get("limit")); err != nil || limit <= 0 || limit > geoJSONMaxLimit {
+			errorResponse(c, http.StatusBadRequest, fmt.Sprintf("limit 应为 1 到 %d 之间的整数", geoJSONMaxLimit))
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	queryBuilder, _, err := buildSearchQuery(service.db, params, userID.(uint), userRole.(string))
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	queryBuilder.WithLocationVisibleTo(userID.(uint), userRole.(string))
+
+	var materials []models.Material
+	err = queryBuilder.Build().Order(materialOrder("taken", "desc")).Limit(limit).Find(&materials).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取素材位置失败")
+		return
+	}
+
+	features := make([]geoJSONFeature, 0, len(materials))
+	for i := range materials {
+		m := &materials[i]
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			ID:   m.ID,
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{*m.Longitude, *m.Latitude},
+			},
+			Properties: map[string]interface{}{
+				"original_filename": m.OriginalFilename,
+				"file_type":         m.FileType,
+				"thumbnail_path":    service.uploadService.GetThumbnailURL(m),
+				"taken_at":          m.TakenAt,
+				"upload_time":       m.UploadTime,
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+}
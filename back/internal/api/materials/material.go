@@ -1,17 +1,26 @@
 package materials
 
 import (
+	"ahsfnu-media-cloud/internal/config"
 	"ahsfnu-media-cloud/internal/database"
 	"ahsfnu-media-cloud/internal/models"
 	"ahsfnu-media-cloud/internal/services"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MaterialService struct {
@@ -20,9 +29,82 @@ type MaterialService struct {
 }
 
 type MaterialQueryBuilder struct {
-	query *gorm.DB
+	query   *gorm.DB
+	tsquery string // 关键词检索的 tsquery，用于相关度排序
 }
 
+// materialSortKey 可排序字段
+type materialSortKey struct {
+	expr     string                               // 排序表达式
+	nullable bool                                 // 表达式可能为 NULL（排在最后）
+	kind     string                               // 游标值类型：time / int / float / string / bool
+	value    func(m *models.Material) interface{} // 取出素材在该字段上的值，用于生成游标
+}
+
+// 可排序字段：请求参数 -> 排序表达式
+var materialSortKeys = map[string]materialSortKey{
+	"upload_time": {expr: "materials.upload_time", kind: "time",
+		value: func(m *models.Material) interface{} { return m.UploadTime }},
+	"taken": {expr: "COALESCE(materials.taken_at, materials.upload_time)", kind: "time",
+		value: func(m *models.Material) interface{} {
+			if m.TakenAt != nil {
+				return *m.TakenAt
+			}
+			return m.UploadTime
+		}},
+	"size": {expr: "materials.file_size", kind: "int",
+		value: func(m *models.Material) interface{} { return m.FileSize }},
+	"filename": {expr: "materials.original_filename", kind: "string",
+		value: func(m *models.Material) interface{} { return m.OriginalFilename }},
+	"duration": {expr: "materials.duration", nullable: true, kind: "int",
+		value: func(m *models.Material) interface{} {
+			if m.Duration == nil {
+				return nil
+			}
+			return *m.Duration
+		}},
+	"starred": {expr: "materials.is_starred", kind: "bool",
+		value: func(m *models.Material) interface{} { return m.IsStarred }},
+	"sharpness": {expr: "materials.sharpness_score", nullable: true, kind: "float",
+		value: func(m *models.Material) interface{} {
+			if m.SharpnessScore == nil {
+				return nil
+			}
+			return *m.SharpnessScore
+		}},
+	"exposure": {expr: "materials.exposure_score", nullable: true, kind: "float",
+		value: func(m *models.Material) interface{} {
+			if m.ExposureScore == nil {
+				return nil
+			}
+			return *m.ExposureScore
+		}},
+}
+
+// normalizeSort 规范化排序参数，未知字段回退到上传时间倒序
+func normalizeSort(sortBy, sortOrder string) (string, string) {
+	if _, ok := materialSortKeys[sortBy]; !ok {
+		return "upload_time", "desc"
+	}
+	if strings.ToLower(sortOrder) == "asc" {
+		return sortBy, "asc"
+	}
+	return sortBy, "desc"
+}
+
+// materialOrder 根据排序参数生成 ORDER BY 子句，以 ID 倒序作为次要排序保证顺序稳定
+func materialOrder(sortBy, sortOrder string) string {
+	sortBy, sortOrder = normalizeSort(sortBy, sortOrder)
+	return materialSortKeys[sortBy].expr + " " + strings.ToUpper(sortOrder) + " NULLS LAST, materials.id DESC"
+}
+
+const (
+	// 按颜色检索时允许的最大 Lab 色差
+	colorMatchDistance = 25.0
+	// 按颜色检索时主色的最小占比
+	colorMatchMinWeight = 0.1
+)
+
 // 单例模式
 var materialService *MaterialService
 
@@ -52,13 +134,38 @@ func checkMaterialPermission(c *gin.Context, material *models.Material) bool {
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("role")
 
-	if material.UploadedBy != userID.(uint) && userRole.(string) != "admin" {
+	if !services.CanEditMaterial(GetMaterialService().db, material, userID.(uint), userRole.(string)) {
 		errorResponse(c, http.StatusForbidden, "没有权限操作此素材")
 		return false
 	}
 	return true
 }
 
+// checkWorkflowUpload 检查当前用户能否向工作流上传或移入素材，失败时写入错误响应
+func checkWorkflowUpload(c *gin.Context, service *MaterialService, workflowID uint) bool {
+	var workflow models.WorkflowGroup
+	if err := service.db.Select("id").First(&workflow, workflowID).Error; err != nil {
+		errorResponse(c, http.StatusNotFound, "工作流不存在")
+		return false
+	}
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !services.HasWorkflowPermission(service.db, workflowID, userID.(uint), userRole.(string), models.WorkflowPermUpload) {
+		errorResponse(c, http.StatusForbidden, "没有权限向该工作流添加素材")
+		return false
+	}
+	return checkWorkflowWritable(c, service, &workflowID)
+}
+
+// checkWorkflowWritable 检查素材所属工作流未归档，已归档的工作流只读，失败时写入错误响应
+func checkWorkflowWritable(c *gin.Context, service *MaterialService, workflowID *uint) bool {
+	if workflowID != nil && services.WorkflowReadOnly(service.db, *workflowID) {
+		errorResponse(c, http.StatusForbidden, "工作流已归档，不能上传、修改或删除其中的素材")
+		return false
+	}
+	return true
+}
+
 // 获取素材工具函数
 func getMaterialByID(service *MaterialService, materialID uint) (*models.Material, bool) {
 	var material models.Material
@@ -89,25 +196,147 @@ func (qb *MaterialQueryBuilder) WithFileType(fileType string) *MaterialQueryBuil
 	return qb
 }
 
+// WithKeyword 全文检索文件名、标签、工作流与上传者；关键词中没有可检索的词元时回退到文件名模糊匹配
 func (qb *MaterialQueryBuilder) WithKeyword(keyword string) *MaterialQueryBuilder {
-	if keyword != "" {
+	if keyword == "" {
+		return qb
+	}
+	tsquery := services.BuildTSQuery(keyword)
+	if tsquery == "" {
 		qb.query = qb.query.Where("original_filename ILIKE ?", "%"+keyword+"%")
+		return qb
 	}
+	qb.tsquery = tsquery
+	qb.query = qb.query.Joins("JOIN material_searches ON material_searches.material_id = materials.id").
+		Where("material_searches.tokens @@ CAST(? AS tsquery)", tsquery)
 	return qb
 }
 
+// RelevanceOrder 返回按检索相关度排序的子句，未进行关键词检索时返回 nil
+func (qb *MaterialQueryBuilder) RelevanceOrder() interface{} {
+	if qb.tsquery == "" {
+		return nil
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank_cd(material_searches.tokens, CAST(? AS tsquery)) DESC, materials.upload_time DESC",
+		Vars:               []interface{}{qb.tsquery},
+		WithoutParentheses: true,
+	}}
+}
+
+// WithTags 按 tags 参数筛选，保持原有语义：含有任一给定标签
 func (qb *MaterialQueryBuilder) WithTags(tagsParam string) *MaterialQueryBuilder {
-	if tagsParam != "" {
-		tagIDs := []uint{}
-		for _, idStr := range SplitAndTrim(tagsParam, ",") {
-			if id, err := strconv.ParseUint(idStr, 10, 32); err == nil {
-				tagIDs = append(tagIDs, uint(id))
-			}
+	return qb.WithAnyTags(tagsParam)
+}
+
+// parseTagIDs 解析逗号分隔的标签ID列表，忽略无效项并去重
+func parseTagIDs(tagsParam string) []uint {
+	tagIDs := []uint{}
+	seen := make(map[uint]bool)
+	for _, idStr := range SplitAndTrim(tagsParam, ",") {
+		if id, err := strconv.ParseUint(idStr, 10, 32); err == nil && !seen[uint(id)] {
+			seen[uint(id)] = true
+			tagIDs = append(tagIDs, uint(id))
 		}
-		if len(tagIDs) > 0 {
-			qb.query = qb.query.Joins("JOIN material_tags ON materials.id = material_tags.material_id").
-				Where("material_tags.tag_id IN ?", tagIDs).Group("materials.id")
+	}
+	return tagIDs
+}
+
+// 标签筛选使用子查询而非 JOIN，避免结果重复以及 GROUP BY 导致 Count 错误
+
+// WithAnyTags 筛选含有任一给定标签的素材
+func (qb *MaterialQueryBuilder) WithAnyTags(tagsParam string) *MaterialQueryBuilder {
+	if tagIDs := parseTagIDs(tagsParam); len(tagIDs) > 0 {
+		qb.query = qb.query.Where("EXISTS (SELECT 1 FROM material_tags mt WHERE mt.material_id = materials.id AND mt.tag_id IN ?)", tagIDs)
+	}
+	return qb
+}
+
+// WithAllTags 筛选同时含有所有给定标签的素材
+func (qb *MaterialQueryBuilder) WithAllTags(tagsParam string) *MaterialQueryBuilder {
+	if tagIDs := parseTagIDs(tagsParam); len(tagIDs) > 0 {
+		qb.query = qb.query.Where("(SELECT COUNT(DISTINCT mt.tag_id) FROM material_tags mt WHERE mt.material_id = materials.id AND mt.tag_id IN ?) = ?",
+			tagIDs, len(tagIDs))
+	}
+	return qb
+}
+
+// WithoutTags 排除含有任一给定标签的素材
+func (qb *MaterialQueryBuilder) WithoutTags(tagsParam string) *MaterialQueryBuilder {
+	if tagIDs := parseTagIDs(tagsParam); len(tagIDs) > 0 {
+		qb.query = qb.query.Where("NOT EXISTS (SELECT 1 FROM material_tags mt WHERE mt.material_id = materials.id AND mt.tag_id IN ?)", tagIDs)
+	}
+	return qb
+}
+
+// WithColor 筛选主色与给定颜色相近的素材（Lab 色差小于 colorMatchDistance 且占比不低于 colorMatchMinWeight）
+func (qb *MaterialQueryBuilder) WithColor(color string) error {
+	if color == "" {
+		return nil
+	}
+	r, g, b, err := services.ParseHexColor(color)
+	if err != nil {
+		return fmt.Errorf("color 参数无效，应为十六进制颜色，如 #2a6bd1")
+	}
+	l, a, bb := services.RGBToLab(r, g, b)
+	qb.query = qb.query.Where(`EXISTS (SELECT 1 FROM material_colors mc WHERE mc.material_id = materials.id
+		AND mc.weight >= ? AND (mc.l - ?) * (mc.l - ?) + (mc.a - ?) * (mc.a - ?) + (mc.b - ?) * (mc.b - ?) <= ?)`,
+		colorMatchMinWeight, l, l, a, a, bb, bb, colorMatchDistance*colorMatchDistance)
+	return nil
+}
+
+// WithScoreRange 按质量得分范围筛选，column 为 sharpness_score 或 exposure_score，
+// name 为请求参数名中的得分名称（min_<name>、max_<name>），用于错误提示
+func (qb *MaterialQueryBuilder) WithScoreRange(column, name, minStr, maxStr string) error {
+	minParam, maxParam := "min_"+name, "max_"+name
+	parse := func(param, value string) (*float64, error) {
+		if value == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%s 参数无效，应为数字", param)
 		}
+		return &v, nil
+	}
+	minValue, err := parse(minParam, minStr)
+	if err != nil {
+		return err
+	}
+	maxValue, err := parse(maxParam, maxStr)
+	if err != nil {
+		return err
+	}
+	if minValue != nil && maxValue != nil && *minValue > *maxValue {
+		return fmt.Errorf("%s 不能大于 %s", minParam, maxParam)
+	}
+	if minValue != nil {
+		qb.query = qb.query.Where(column+" >= ?", *minValue)
+	}
+	if maxValue != nil {
+		qb.query = qb.query.Where(column+" <= ?", *maxValue)
+	}
+	return nil
+}
+
+// WithRejectable 只保留可能为废片的素材（模糊或曝光严重失衡）
+func (qb *MaterialQueryBuilder) WithRejectable() *MaterialQueryBuilder {
+	cfg := config.AppConfig.Quality
+	qb.query = qb.query.Where("(sharpness_score < ? OR exposure_score < ?)", cfg.BlurThreshold, cfg.ExposureThreshold)
+	return qb
+}
+
+// visibleCondition 普通用户可见素材的查询条件：自己的、公开的以及所在工作流中的素材
+func visibleCondition(userID uint) (string, []interface{}) {
+	return "(materials.uploaded_by = ? OR materials.is_public = ? OR " + services.WorkflowMemberCondition + ")",
+		[]interface{}{userID, true, userID, userID}
+}
+
+// WithVisibleTo 按用户角色过滤可见素材：管理员可见全部，普通用户可以看到自己的、公开的以及所在工作流中的素材
+func (qb *MaterialQueryBuilder) WithVisibleTo(userID uint, role string) *MaterialQueryBuilder {
+	if role != "admin" {
+		condition, args := visibleCondition(userID)
+		qb.query = qb.query.Where(condition, args...)
 	}
 	return qb
 }
@@ -142,6 +371,51 @@ func successResponse(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, gin.H{"data": data})
 }
 
+// fillFileURLs 将文件与缩略图路径转换为访问URL，不能直接访问的文件为空
+func fillFileURLs(material *models.Material) {
+	service := GetMaterialService()
+	material.FilePath = service.uploadService.GetFileURL(material)
+	if material.ThumbnailPath != "" {
+		material.ThumbnailPath = service.uploadService.GetThumbnailURL(material)
+	}
+	material.ProcessedPath = service.uploadService.GetProcessedURL(material)
+}
+
+// fillMaterialURL 为登录用户填充访问URL，加密素材的原始文件与成品文件通过需要登录的接口解密获取
+func fillMaterialURL(material *models.Material) {
+	processed := material.ProcessedPath
+	fillFileURLs(material)
+	if !material.Encrypted {
+		return
+	}
+	if material.FilePath == "" && material.StorageTier != services.StorageTierCold {
+		material.FilePath = fmt.Sprintf("/api/v1/materials/%d/download", material.ID)
+	}
+	if processed != "" {
+		material.ProcessedPath = fmt.Sprintf("/api/v1/materials/%d/processed", material.ID)
+	}
+}
+
+// MaterialResponses 填充文件URL并转换为安全的响应格式
+func MaterialResponses(materials []models.Material) []models.MaterialResponse {
+	responses := []models.MaterialResponse{}
+	for i := range materials {
+		fillMaterialURL(&materials[i])
+		responses = append(responses, *materials[i].ToMaterialResponse())
+	}
+	return responses
+}
+
+// PublicMaterialResponses 填充文件URL并转换为面向未登录访问者的响应格式
+func PublicMaterialResponses(materials []models.Material) []models.PublicMaterialResponse {
+	responses := []models.PublicMaterialResponse{}
+	for i := range materials {
+		fillFileURLs(&materials[i])
+		responses = append(responses, *materials[i].ToPublicMaterialResponse())
+	}
+	return responses
+}
+
 // parseTagIDsFromForm 解析上传表单中的标签参数，支持以下形式：
 // - tag_ids: 逗号分隔的ID字符串，例如 "1,2,3"
 // - tags: 同上，向后兼容
@@ -196,6 +470,9 @@ func UploadMaterial(c *gin.Context) {
 			workflowID = &workflowIDUint
 		}
 	}
+	if workflowID != nil && !checkWorkflowUpload(c, service, *workflowID) {
+		return
+	}
 
 	// 获取上传的文件
 	file, err := c.FormFile("file")
@@ -204,8 +481,30 @@ func UploadMaterial(c *gin.Context) {
 		return
 	}
 
-	// 解析表单中的标签ID（可选）
+	// 解析表单中的标签ID（可选），并加上所属工作流的默认标签
 	tagIDs := parseTagIDsFromForm(c)
+	var schema []models.CustomFieldDefinition
+	if workflowID != nil {
+		var workflow models.WorkflowGroup
+		if err := service.db.Select("id, default_tags, custom_field_schema").First(&workflow, *workflowID).Error; err == nil {
+			tagIDs = append(tagIDs, workflow.DefaultTagIDs()...)
+			schema = workflow.CustomFields()
+		}
+	}
+
+	// 自定义字段取值（可选，JSON 对象），按所属工作流的字段定义校验，必填字段须在上传时填写
+	customFields := make(map[string]interface{})
+	if raw := c.PostForm("custom_fields"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &customFields); err != nil {
+			errorResponse(c, http.StatusBadRequest, "custom_fields 必须是 JSON 对象")
+			return
+		}
+	}
+	customFields, err = services.ValidateCustomFieldValues(service.db, schema, customFields)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 使用数据库事务确保一致性
 	tx := service.db.Begin()
@@ -226,6 +525,40 @@ func UploadMaterial(c *gin.Context) {
 	// 确保新上传的素材默认为私有（非公开）
 	material.IsPublic = false
 
+	// 可选的图注与描述
+	material.Caption = strings.TrimSpace(c.PostForm("caption"))
+	material.Description = c.PostForm("description")
+	material.CustomFields = services.EncodeCustomFieldValues(customFields)
+
+	// 素材级元数据去除策略（可选，未设置时继承工作流）
+	if stripStr := c.PostForm("strip_metadata"); stripStr != "" {
+		if strip, err := strconv.ParseBool(stripStr); err == nil {
+			material.StripMetadata = &strip
+		}
+	}
+
+	// 可选：加密存储原始文件（需在生成缩略图之后、生成公开副本之前进行，公开副本随之加密存储）
+	if encrypt, _ := strconv.ParseBool(c.PostForm("encrypt")); encrypt {
+		if !services.EncryptionEnabled() {
+			tx.Rollback()
+			service.uploadService.DeleteFile(material)
+			errorResponse(c, http.StatusBadRequest, "服务器未配置加密主密钥")
+			return
+		}
+		if err := service.uploadService.EncryptOriginal(material); err != nil {
+			tx.Rollback()
+			service.uploadService.DeleteFile(material)
+			errorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := service.uploadService.ApplyMetadataPolicy(tx, material); err != nil {
+		tx.Rollback()
+		service.uploadService.DeleteFile(material)
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	// 保存到数据库
 	if err := tx.Create(material).Error; err != nil {
 		tx.Rollback()
@@ -259,14 +592,26 @@ func UploadMaterial(c *gin.Context) {
 		return
 	}
 
+	// 根据拍摄位置标记命名地点
+	if err := services.TagMaterialPlaces(service.db, material); err != nil {
+		log.Printf("标记素材 %d 所在地点失败: %v", material.ID, err)
+	}
+
+	// 建立检索索引
+	if err := services.IndexMaterial(service.db, material.ID); err != nil {
+		log.Printf("建立素材 %d 检索索引失败: %v", material.ID, err)
+	}
+
+	// 在后台执行所属工作流的处理流程
+	if workflowID != nil {
+		service.uploadService.RunPipelineAsync(service.db, []uint{material.ID}, models.PipelineTriggerUpload, nil)
+	}
+
 	// 预加载关联数据
 	service.db.Preload("Uploader").Preload("MaterialTags").Preload("MaterialTags.Tag").First(material, material.ID)
 
 	// 添加文件URL
-	material.FilePath = service.uploadService.GetFileURL(material)
-	if material.ThumbnailPath != "" {
-		material.ThumbnailPath = service.uploadService.GetThumbnailURL(material)
-	}
+	fillMaterialURL(material)
 
 	// 转换为安全的响应格式
 	materialResponse := material.ToMaterialResponse()
@@ -292,14 +637,21 @@ func UpdateMaterial(c *gin.Context) {
 	if !checkMaterialPermission(c, material) {
 		return
 	}
+	if !checkWorkflowWritable(c, service, material.WorkflowID) {
+		return
+	}
 
 	// 更新字段
 	var updateData struct {
-		OriginalFilename string `json:"original_filename"`
-		IsStarred        *bool  `json:"is_starred"`
-		IsPublic         *bool  `json:"is_public"`
-		WorkflowID       *uint  `json:"workflow_id"`
-		TagIDs           []uint `json:"tag_ids"` // 标签ID列表，用于更新素材的标签
+		OriginalFilename string                 `json:"original_filename"`
+		IsStarred        *bool                  `json:"is_starred"`
+		IsPublic         *bool                  `json:"is_public"`
+		WorkflowID       optionalID             `json:"workflow_id"`    // 未传入时不修改，传入 null 时移出工作流
+		StripMetadata    *bool                  `json:"strip_metadata"` // 素材级元数据去除策略
+		TagIDs           []uint                 `json:"tag_ids"`        // 标签ID列表，用于更新素材的标签
+		Caption          *string                `json:"caption"`
+		Description      *string                `json:"description"`
+		CustomFields     map[string]interface{} `json:"custom_fields"` // 自定义字段取值，只更新传入的字段，值为 null 时清除
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -318,14 +670,83 @@ func UpdateMaterial(c *gin.Context) {
 	if updateData.IsPublic != nil {
 		updates["is_public"] = *updateData.IsPublic
 	}
-	if updateData.WorkflowID != nil || (updateData.WorkflowID == nil && updateData.WorkflowID != material.WorkflowID) {
-		updates["workflow_id"] = updateData.WorkflowID
+	// 只有显式传入且与当前不同的 workflow_id 才视为更换工作流
+	workflowChanged := updateData.WorkflowID.Set && !sameWorkflow(material.WorkflowID, updateData.WorkflowID.Value)
+	if workflowChanged {
+		if updateData.WorkflowID.Value != nil && !checkWorkflowUpload(c, service, *updateData.WorkflowID.Value) {
+			return
+		}
+		updates["workflow_id"] = updateData.WorkflowID.Value
+	}
+	// 更换或移出工作流后审核状态重新开始
+	resetReview := workflowChanged && material.ReviewStatus != ""
+	if resetReview {
+		updates["review_status"] = ""
+	}
+	if updateData.StripMetadata != nil {
+		updates["strip_metadata"] = *updateData.StripMetadata
+	}
+	if updateData.Caption != nil {
+		updates["caption"] = strings.TrimSpace(*updateData.Caption)
+	}
+	if updateData.Description != nil {
+		updates["description"] = *updateData.Description
+	}
+
+	// 按（更新后）所属工作流的字段定义处理自定义字段
+	targetWorkflowID := material.WorkflowID
+	if workflowChanged {
+		targetWorkflowID = updateData.WorkflowID.Value
+	}
+	var schema []models.CustomFieldDefinition
+	if targetWorkflowID != nil {
+		var workflow models.WorkflowGroup
+		if err := service.db.First(&workflow, *targetWorkflowID).Error; err == nil {
+			schema = workflow.CustomFields()
+		}
+	}
+	// 丢弃（新）工作流中已不存在的字段后合并传入的取值，填写字段或更换工作流时校验必填字段
+	values := services.FilterCustomFieldValues(schema, material.CustomFieldValues())
+	for key, value := range updateData.CustomFields {
+		values[key] = value
+	}
+	if updateData.CustomFields != nil || workflowChanged {
+		normalized, err := services.ValidateCustomFieldValues(service.db, schema, values)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates["custom_fields"] = services.EncodeCustomFieldValues(normalized)
 	}
 
 	if err := service.db.Model(&material).Updates(updates).Error; err != nil {
 		errorResponse(c, http.StatusInternalServerError, "更新素材失败")
 		return
 	}
+	if resetReview {
+		userID, _ := c.Get("user_id")
+		reason := "素材移出工作流"
+		if updateData.WorkflowID.Value != nil {
+			reason = "素材更换工作流"
+		}
+		reviewLog := models.MaterialReviewLog{
+			MaterialID: material.ID,
+			WorkflowID: material.WorkflowID,
+			FromStatus: material.ReviewStatus,
+			Reason:     reason,
+			ActorID:    userID.(uint),
+		}
+		if err := service.db.Create(&reviewLog).Error; err != nil {
+			log.Printf("记录素材 %d 审核状态变更失败: %v", materialID, err)
+		}
+	}
+
+	// 工作流或策略可能已变化，重新读取后同步公开副本
+	service.db.First(material, materialID)
+	if err := service.uploadService.ApplyMetadataPolicy(service.db, material); err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	// 处理标签更新
 	if updateData.TagIDs != nil {
@@ -354,12 +775,14 @@ func UpdateMaterial(c *gin.Context) {
 		}
 	}
 
+	// 同步检索索引
+	if err := services.IndexMaterial(service.db, materialID); err != nil {
+		log.Printf("更新素材 %d 检索索引失败: %v", materialID, err)
+	}
+
 	// 重新获取更新后的数据
 	service.db.Preload("Uploader").Preload("MaterialTags").Preload("MaterialTags.Tag").First(material, materialID)
-	material.FilePath = service.uploadService.GetFileURL(material)
-	if material.ThumbnailPath != "" {
-		material.ThumbnailPath = service.uploadService.GetThumbnailURL(material)
-	}
+	fillMaterialURL(material)
 
 	// 转换为安全的响应格式
 	materialResponse := material.ToMaterialResponse()
@@ -384,17 +807,14 @@ func GetMaterialDetails(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("role")
 
-	// 检查权限：只有素材所有者、管理员或者公开素材可以被查看
-	if material.UploadedBy != userID.(uint) && userRole.(string) != "admin" && !material.IsPublic {
+	// 检查权限：素材所有者、管理员、所属工作流成员或者公开素材可以被查看
+	if !canViewMaterial(service.db, material, userID.(uint), userRole.(string)) {
 		errorResponse(c, http.StatusForbidden, "没有权限查看此素材")
 		return
 	}
 
 	// 添加文件URL
-	material.FilePath = service.uploadService.GetFileURL(material)
-	if material.ThumbnailPath != "" {
-		material.ThumbnailPath = service.uploadService.GetThumbnailURL(material)
-	}
+	fillMaterialURL(material)
 
 	// 转换为安全的响应格式
 	materialResponse := material.ToMaterialResponse()
@@ -425,17 +845,14 @@ func GetMaterial(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("role")
 
-	// 检查权限：只有素材所有者、管理员或者公开素材可以被查看
-	if material.UploadedBy != userID.(uint) && userRole.(string) != "admin" && !material.IsPublic {
+	// 检查权限：素材所有者、管理员、所属工作流成员或者公开素材可以被查看
+	if !canViewMaterial(service.db, &material, userID.(uint), userRole.(string)) {
 		errorResponse(c, http.StatusForbidden, "没有权限查看此素材")
 		return
 	}
 
 	// 添加文件URL
-	material.FilePath = service.uploadService.GetFileURL(&material)
-	if material.ThumbnailPath != "" {
-		material.ThumbnailPath = service.uploadService.GetThumbnailURL(&material)
-	}
+	fillMaterialURL(&material)
 
 	// 转换为安全的响应格式
 	materialResponse := material.ToMaterialResponse()
@@ -467,10 +884,13 @@ func DeleteMaterial(c *gin.Context) {
 	// 检查权限
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("role")
-	if material.UploadedBy != userID.(uint) && userRole.(string) != "admin" {
+	if !services.CanEditMaterial(service.db, &material, userID.(uint), userRole.(string)) {
 		errorResponse(c, http.StatusForbidden, "没有权限删除此素材")
 		return
 	}
+	if !checkWorkflowWritable(c, service, material.WorkflowID) {
+		return
+	}
 
 	// 删除文件
 	if err := service.uploadService.DeleteFile(&material); err != nil {
@@ -484,77 +904,567 @@ func DeleteMaterial(c *gin.Context) {
 		return
 	}
 
+	// 删除素材主色记录
+	if err := service.db.Where("material_id = ?", materialID).Delete(&models.MaterialColor{}).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除素材主色记录失败")
+		return
+	}
+
+	// 删除素材地点标记
+	if err := service.db.Where("material_id = ?", materialID).Delete(&models.MaterialPlace{}).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除素材地点标记失败")
+		return
+	}
+
+	// 删除素材评论
+	if err := service.db.Where("material_id = ?", materialID).Delete(&models.MaterialComment{}).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除素材评论失败")
+		return
+	}
+
+	// 删除审核记录
+	if err := service.db.Where("material_id = ?", materialID).Delete(&models.MaterialReviewLog{}).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除素材审核记录失败")
+		return
+	}
+
+	// 删除处理流程执行记录
+	if err := services.DeletePipelineRuns(service.db, "material_id = ?", materialID); err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除素材处理记录失败")
+		return
+	}
+
+	// 从相册中移除，并清除以其为封面的设置
+	if err := service.db.Where("material_id = ?", materialID).Delete(&models.AlbumItem{}).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "删除相册素材关联失败")
+		return
+	}
+	service.db.Model(&models.Album{}).Where("cover_material_id = ?", materialID).Update("cover_material_id", nil)
+
 	// 删除数据库记录
 	if err := service.db.Delete(&material).Error; err != nil {
 		errorResponse(c, http.StatusInternalServerError, "删除素材记录失败")
 		return
 	}
 
+	// 删除检索索引
+	if err := services.RemoveMaterialIndex(service.db, material.ID); err != nil {
+		log.Printf("删除素材 %d 检索索引失败: %v", material.ID, err)
+	}
+
 	successResponse(c, gin.H{"message": "素材删除成功"})
 }
 
-// GetMaterials 获取素材列表
+// SearchMaterialFilterParams 可用于筛选素材的查询参数（不含分页与排序），智能相册只保存这些参数
+var SearchMaterialFilterParams = []string{
+	"workflow_id", "file_type", "keyword", "q",
+	"tags", "tags_all", "tags_any", "tags_none",
+	"taken_from", "taken_to", "color",
+	"bbox", "near", "radius", "place_id",
+	"min_sharpness", "max_sharpness", "min_exposure", "max_exposure", "rejectable",
+	"review_status",
+}
+
+// buildSearchQuery 根据筛选参数构建查询，返回查询构建器与最终的全文检索关键词
+func buildSearchQuery(db *gorm.DB, params url.Values, userID uint, role string) (*MaterialQueryBuilder, string, error) {
+	keyword := params.Get("keyword")
+
+	// 解析查询语言，其中的自由文本并入关键词
+	var parsedQuery *MaterialQuery
+	if q := params.Get("q"); q != "" {
+		var err error
+		if parsedQuery, err = ParseMaterialQuery(q); err != nil {
+			return nil, "", err
+		}
+		keyword = strings.TrimSpace(keyword + " " + parsedQuery.Keyword)
+	}
+
+	// 使用查询构建器
+	queryBuilder := NewMaterialQueryBuilder(db)
+	queryBuilder.
+		WithWorkflow(params.Get("workflow_id")).
+		WithFileType(params.Get("file_type")).
+		WithKeyword(keyword).
+		WithTags(params.Get("tags")).
+		WithAllTags(params.Get("tags_all")).
+		WithAnyTags(params.Get("tags_any")).
+		WithoutTags(params.Get("tags_none")).
+		WithReviewStatus(params.Get("review_status")).
+		WithVisibleTo(userID, role)
+	if err := queryBuilder.WithColor(params.Get("color")); err != nil {
+		return nil, "", err
+	}
+	err := queryBuilder.WithScoreRange("sharpness_score", "sharpness", params.Get("min_sharpness"), params.Get("max_sharpness"))
+	if err != nil {
+		return nil, "", err
+	}
+	err = queryBuilder.WithScoreRange("exposure_score", "exposure", params.Get("min_exposure"), params.Get("max_exposure"))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := queryBuilder.WithTakenRange(params.Get("taken_from"), params.Get("taken_to")); err != nil {
+		return nil, "", err
+	}
+	err = queryBuilder.withGeoFilters(params.Get("bbox"), params.Get("near"), params.Get("radius"), params.Get("place_id"), userID, role)
+	if err != nil {
+		return nil, "", err
+	}
+	if params.Get("rejectable") == "true" {
+		queryBuilder.WithRejectable()
+	}
+	if parsedQuery != nil {
+		queryBuilder.WithParsedQuery(parsedQuery)
+	}
+	return queryBuilder, keyword, nil
+}
+
+// respondSearchError 返回搜索参数错误，查询语言错误附带出错位置
+func respondSearchError(c *gin.Context, err error) {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    qe.Error(),
+			"token":    qe.Token,
+			"position": qe.Position,
+		})
+		return
+	}
+	errorResponse(c, http.StatusBadRequest, err.Error())
+}
+
+// ValidateSearchParams 检查筛选参数能否正确解析
+func ValidateSearchParams(params url.Values) error {
+	_, _, err := buildSearchQuery(GetMaterialService().db, params, 0, "")
+	return err
+}
+
+// CountSearchMatches 统计满足筛选参数的可见素材数量，since 不为空时只统计此后上传的素材
+func CountSearchMatches(params url.Values, userID uint, role string, since *time.Time) (int64, error) {
+	queryBuilder, _, err := buildSearchQuery(GetMaterialService().db, params, userID, role)
+	if err != nil {
+		return 0, err
+	}
+	query := queryBuilder.Build()
+	if since != nil {
+		query = query.Where("materials.upload_time > ?", *since)
+	}
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
+
+// SearchMaterials 搜索素材列表
 func SearchMaterials(c *gin.Context) {
+	SearchMaterialsWithParams(c, c.Request.URL.Query())
+}
+
+// SearchMaterialsWithParams 按给定参数搜索素材并写入响应
+func SearchMaterialsWithParams(c *gin.Context, params url.Values) {
 	service := GetMaterialService()
 
-	// 获取查询参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	workflowID := c.Query("workflow_id")
-	fileType := c.Query("file_type")
-	keyword := c.Query("keyword")
-	tagsParam := c.Query("tags")
+	// 获取分页与排序参数
+	page, _ := strconv.Atoi(params.Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := 20
+	if params.Has("page_size") {
+		pageSize, _ = strconv.Atoi(params.Get("page_size"))
+	}
+	sortBy := params.Get("sort_by")
+	sortOrder := params.Get("sort_order")
 
 	// 获取当前用户信息
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("role")
 
-	// 使用查询构建器
-	queryBuilder := NewMaterialQueryBuilder(service.db)
-	query := queryBuilder.
-		WithWorkflow(workflowID).
-		WithFileType(fileType).
-		WithKeyword(keyword).
-		WithTags(tagsParam).
-		Build()
+	queryBuilder, keyword, err := buildSearchQuery(service.db, params, userID.(uint), userRole.(string))
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	query := queryBuilder.Build()
+
+	// 可选的分面统计
+	var facets map[string][]FacetBucket
+	if facetsParam := params.Get("facets"); facetsParam != "" {
+		facets, err = computeFacets(service.db, params, SplitAndTrim(facetsParam, ","), userID.(uint), userRole.(string))
+		if err != nil {
+			respondSearchError(c, err)
+			return
+		}
+	}
 
-	// 根据用户角色和权限过滤素材
-	if userRole.(string) == "admin" {
-		// 管理员可以看到所有素材
-		// 不需要额外过滤
-	} else {
-		// 普通用户只能看到自己的素材和公开的素材
-		query = query.Where("(uploaded_by = ? OR is_public = ?)", userID.(uint), true)
+	if keyword != "" {
+		// 高亮需要与建立索引时相同的关联数据
+		query = query.Preload("Workflow").Preload("Places.Place")
 	}
+	query = query.Preload("Uploader").Preload("MaterialTags").Preload("MaterialTags.Tag")
 
-	// 分页
-	offset := (page - 1) * pageSize
+	// 传入 cursor 参数（首页可为空）时使用游标分页
+	cursorParam, cursorMode := params.Get("cursor"), params.Has("cursor")
 	var materials []models.Material
 	var total int64
-
-	query.Count(&total)
-	err := query.Preload("Uploader").Preload("MaterialTags").Preload("MaterialTags.Tag").Offset(offset).Limit(pageSize).Order("upload_time DESC").Find(&materials).Error
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, "获取素材列表失败")
-		return
+	var nextCursor *string
+	if cursorMode {
+		if cursorParam == "" && sortBy == "" && queryBuilder.RelevanceOrder() != nil {
+			// 游标只记录排序字段的值，无法延续按相关度的排序
+			errorResponse(c, http.StatusBadRequest, "关键词检索按相关度排序时不支持游标分页，请指定 sort_by 或使用 page 分页")
+			return
+		}
+		if pageSize <= 0 {
+			pageSize = 20
+		}
+		var cursor *materialCursor
+		if cursorParam != "" {
+			var err error
+			if cursor, err = decodeMaterialCursor(cursorParam); err != nil {
+				errorResponse(c, http.StatusBadRequest, "无效的游标")
+				return
+			}
+			// 后续页沿用游标中的排序方式
+			sortBy, sortOrder = cursor.SortBy, cursor.SortOrder
+		}
+		sortBy, sortOrder = normalizeSort(sortBy, sortOrder)
+		if cursor != nil {
+			query = applyMaterialCursor(query, cursor)
+		}
+		// 多取一条判断是否还有下一页
+		err := query.Order(materialOrder(sortBy, sortOrder)).Limit(pageSize + 1).Find(&materials).Error
+		if err != nil {
+			errorResponse(c, http.StatusInternalServerError, "获取素材列表失败")
+			return
+		}
+		if len(materials) > pageSize {
+			materials = materials[:pageSize]
+			next := encodeMaterialCursor(sortBy, sortOrder, &materials[pageSize-1])
+			nextCursor = &next
+		}
+	} else {
+		// 分页
+		offset := (page - 1) * pageSize
+		query.Count(&total)
+		var order interface{} = materialOrder(sortBy, sortOrder)
+		if relevance := queryBuilder.RelevanceOrder(); relevance != nil && sortBy == "" {
+			// 关键词检索且未指定排序时按相关度排序
+			order = relevance
+		}
+		err := query.Offset(offset).Limit(pageSize).Order(order).Find(&materials).Error
+		if err != nil {
+			errorResponse(c, http.StatusInternalServerError, "获取素材列表失败")
+			return
+		}
 	}
 
 	// 转换为安全的响应格式
 	var materialResponses []models.MaterialResponse
 	for i := range materials {
 		// 添加文件URL
-		materials[i].FilePath = service.uploadService.GetFileURL(&materials[i])
-		if materials[i].ThumbnailPath != "" {
-			materials[i].ThumbnailPath = service.uploadService.GetThumbnailURL(&materials[i])
-		}
+		fillMaterialURL(&materials[i])
 		// 转换为安全的响应格式
 		materialResponse := materials[i].ToMaterialResponse()
+		if keyword != "" {
+			materialResponse.Highlights = services.MaterialHighlights(&materials[i], keyword)
+		}
 		materialResponses = append(materialResponses, *materialResponse)
 	}
 
+	pagination := gin.H{
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	}
+	if cursorMode {
+		pagination = gin.H{
+			"page_size":   pageSize,
+			"next_cursor": nextCursor,
+		}
+	}
+	response := gin.H{
+		"data":       materialResponses,
+		"pagination": pagination,
+	}
+	if facets != nil {
+		response["facets"] = facets
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadMaterial 下载素材原始文件，加密文件在传输过程中即时解密
+func DownloadMaterial(c *gin.Context) {
+	service := GetMaterialService()
+
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return
+	}
+
+	material, found := getMaterialByID(service, materialID)
+	if !found {
+		errorResponse(c, http.StatusNotFound, "素材不存在")
+		return
+	}
+
+	// 检查权限：能查看素材的用户即可下载
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !canViewMaterial(service.db, material, userID.(uint), userRole.(string)) {
+		errorResponse(c, http.StatusForbidden, "没有权限下载此素材")
+		return
+	}
+
+	ServeOriginal(c, material)
+}
+
+// ServeOriginal 输出素材原始文件，调用方负责权限检查
+func ServeOriginal(c *gin.Context, material *models.Material) {
+	service := GetMaterialService()
+
+	if material.StorageTier == services.StorageTierCold {
+		errorResponse(c, http.StatusConflict, "素材原始文件位于冷存储，请先恢复")
+		return
+	}
+
+	reader, err := service.uploadService.OpenOriginal(material)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "读取文件失败")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, material.FileSize, material.MimeType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": material.OriginalFilename}),
+	})
+}
+
+// DownloadProcessed 下载处理流程的成品文件，加密素材的成品文件在读取时解密
+func DownloadProcessed(c *gin.Context) {
+	service := GetMaterialService()
+
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return
+	}
+
+	material, found := getMaterialByID(service, materialID)
+	if !found {
+		errorResponse(c, http.StatusNotFound, "素材不存在")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !canViewMaterial(service.db, material, userID.(uint), userRole.(string)) {
+		errorResponse(c, http.StatusForbidden, "没有权限下载此素材")
+		return
+	}
+	if material.ProcessedPath == "" {
+		errorResponse(c, http.StatusNotFound, "素材没有处理成品")
+		return
+	}
+
+	reader, err := service.uploadService.OpenProcessed(material)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "读取文件失败")
+		return
+	}
+	defer reader.Close()
+
+	ext := filepath.Ext(material.ProcessedPath)
+	filename := strings.TrimSuffix(material.OriginalFilename, filepath.Ext(material.OriginalFilename)) + ext
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+	})
+}
+
+// ServeShareable 输出可对外提供的文件：需要去除元数据时输出公开副本，否则输出原始文件，调用方负责权限检查
+func ServeShareable(c *gin.Context, material *models.Material) {
+	service := GetMaterialService()
+
+	if material.PublicPath == "" {
+		if services.StripRequired(service.db, material) {
+			errorResponse(c, http.StatusConflict, "素材的公开副本尚未生成")
+			return
+		}
+		ServeOriginal(c, material)
+		return
+	}
+
+	ext := filepath.Ext(material.PublicPath)
+	filename := strings.TrimSuffix(material.OriginalFilename, filepath.Ext(material.OriginalFilename)) + ext
+	if !material.Encrypted {
+		c.FileAttachment(service.uploadService.LocalPath(material.PublicPath), filename)
+		return
+	}
+
+	// 加密素材的公开副本同样加密存储，读取时解密
+	reader, err := service.uploadService.OpenPublic(material)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "读取文件失败")
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+	})
+}
+
+// ServeThumbnail 输出素材缩略图，调用方负责权限检查
+func ServeThumbnail(c *gin.Context, material *models.Material) {
+	service := GetMaterialService()
+
+	if material.ThumbnailPath == "" {
+		errorResponse(c, http.StatusNotFound, "素材没有缩略图")
+		return
+	}
+	c.File(service.uploadService.LocalPath(material.ThumbnailPath))
+}
+
+// ServeUpload 提供 /uploads 下文件的访问，原始文件只有在无需去除元数据且未加密时才能直接访问，
+// 其余情况须通过下载接口按权限获取
+func ServeUpload(c *gin.Context) {
+	service := GetMaterialService()
+
+	fullPath, ok := service.uploadService.ResolveUpload(service.db, c.Param("filepath"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.File(fullPath)
+}
+
+// RestoreMaterial 请求将冷存储中的原始文件恢复到热存储，恢复在后台进行
+func RestoreMaterial(c *gin.Context) {
+	service := GetMaterialService()
+
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return
+	}
+
+	material, found := getMaterialByID(service, materialID)
+	if !found {
+		errorResponse(c, http.StatusNotFound, "素材不存在")
+		return
+	}
+
+	// 能查看素材的用户即可请求恢复
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !canViewMaterial(service.db, material, userID.(uint), userRole.(string)) {
+		errorResponse(c, http.StatusForbidden, "没有权限操作此素材")
+		return
+	}
+
+	if material.StorageTier != services.StorageTierCold {
+		errorResponse(c, http.StatusBadRequest, "素材不在冷存储中")
+		return
+	}
+
+	cold, err := services.NewColdStorage()
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// 已在恢复中时不重复发起
+	claimed, err := services.ClaimRestore(service.db, material.ID)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "更新恢复状态失败")
+		return
+	}
+	if claimed {
+		go func(m models.Material) {
+			if err := service.uploadService.RestoreToHot(service.db, cold, &m); err != nil {
+				log.Printf("恢复素材 %d 失败: %v", m.ID, err)
+				service.db.Model(&models.Material{}).Where("id = ?", m.ID).Update("restore_status", services.RestoreStatusFailed)
+			}
+		}(*material)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": gin.H{
+		"id":             material.ID,
+		"storage_tier":   material.StorageTier,
+		"restore_status": services.RestoreStatusRestoring,
+	}})
+}
+
+// GetRejectableMaterials 获取工作流中可能为废片的图片，最模糊的排在最前，便于审核人员快速筛选
+func GetRejectableMaterials(c *gin.Context) {
+	service := GetMaterialService()
+
+	workflowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "无效的工作流ID")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	query := NewMaterialQueryBuilder(service.db).
+		WithWorkflow(strconv.FormatUint(workflowID, 10)).
+		WithFileType("image").
+		WithRejectable().
+		WithVisibleTo(userID.(uint), userRole.(string)).
+		Build()
+
+	var materials []models.Material
+	var total int64
+	query.Count(&total)
+	err = query.Offset((page - 1) * pageSize).Limit(pageSize).
+		Order("materials.sharpness_score ASC NULLS LAST, materials.exposure_score ASC NULLS LAST").
+		Find(&materials).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取待筛选素材失败")
+		return
+	}
+
+	var materialResponses []models.MaterialResponse
+	for i := range materials {
+		fillMaterialURL(&materials[i])
+		materialResponses = append(materialResponses, *materials[i].ToMaterialResponse())
+	}
+
 	paginatedResponse(c, materialResponses, page, pageSize, total)
 }
 
+// optionalID 可选的ID字段，用于区分请求中未传入与显式传入 null
+type optionalID struct {
+	Set   bool
+	Value *uint
+}
+
+// UnmarshalJSON 字段出现在请求中（包括 null）时记为已传入
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// sameWorkflow 两个工作流ID是否相同（均为空视为相同）
+func sameWorkflow(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 // SplitAndTrim 工具函数：分割字符串并去除空格
 func SplitAndTrim(s, sep string) []string {
 	res := []string{}
@@ -0,0 +1,111 @@
+package materials
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB 只生成 SQL、不连接数据库的 gorm 实例，用于测试查询构建
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestOptionalIDUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		set     bool
+		value   *uint
+		wantErr bool
+	}{
+		{"未传入", `{}`, false, nil, false},
+		{"显式 null", `{"workflow_id": null}`, true, nil, false},
+		{"传入ID", `{"workflow_id": 7}`, true, uintPtr(7), false},
+		{"类型错误", `{"workflow_id": "7"}`, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req struct {
+				WorkflowID optionalID `json:"workflow_id"`
+			}
+			err := json.Unmarshal([]byte(tt.body), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if req.WorkflowID.Set != tt.set {
+				t.Fatalf("Set 为 %v，期望 %v", req.WorkflowID.Set, tt.set)
+			}
+			if !sameWorkflow(req.WorkflowID.Value, tt.value) {
+				t.Fatalf("Value 为 %v，期望 %v", req.WorkflowID.Value, tt.value)
+			}
+		})
+	}
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestWithColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		wantErr bool
+	}{
+		{"", false},
+		{"#2a6bd1", false},
+		{"2A6BD1", false},
+		{"#fff", false},
+		{"#12345", true},
+		{"#gggggg", true},
+		{"red", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.color, func(t *testing.T) {
+			err := NewMaterialQueryBuilder(dryRunDB(t)).WithColor(tt.color)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithScoreRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max string
+		wantErr  bool
+	}{
+		{"未指定", "", "", false},
+		{"只有下限", "12.5", "", false},
+		{"只有上限", "", "80", false},
+		{"上下限", "10", "80", false},
+		{"下限不是数字", "abc", "", true},
+		{"上限不是数字", "", "1e", true},
+		{"NaN", "NaN", "", true},
+		{"无穷大", "", "Inf", true},
+		{"下限大于上限", "90", "10", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewMaterialQueryBuilder(dryRunDB(t)).WithScoreRange("sharpness_score", "sharpness", tt.min, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
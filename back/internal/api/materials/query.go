@@ -0,0 +1,347 @@
+package materials
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/services"
+)
+
+// 素材搜索查询语言
+//
+// 查询由空白分隔的若干项组成，例如：
+//
+//	tag:迎新 type:video uploader:zhang taken:2024-09..2024-10 size:>50MB starred:true -tag:废片 晚会
+//
+// 带字段前缀的项转换为对应的筛选条件，前缀 "-" 表示取反；
+// 不带字段的项作为全文检索关键词。值中含空格时可用双引号包裹，如 tag:"开学 典礼"。
+
+// QueryError 查询语法错误，Position 为出错项在查询中的字符位置（从 1 开始）
+type QueryError struct {
+	Position int
+	Token    string
+	Message  string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("查询语法错误（第 %d 个字符 \"%s\"）: %s", e.Position, e.Token, e.Message)
+}
+
+// queryTerm 查询中的一项
+type queryTerm struct {
+	raw      string
+	position int
+	negate   bool
+	field    string // 为空表示全文检索关键词
+	value    string
+}
+
+// queryCondition 一项筛选条件对应的 SQL
+type queryCondition struct {
+	negate bool
+	sql    string
+	args   []interface{}
+}
+
+// MaterialQuery 解析后的查询
+type MaterialQuery struct {
+	Keyword       string   // 全文检索关键词
+	ExcludedWords []string // 需排除的关键词
+	conditions    []queryCondition
+}
+
+var queryFieldPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// 支持的字段及其解析函数
+var queryFieldParsers = map[string]func(value string) (string, []interface{}, error){
+	"tag":      parseTagCondition,
+	"type":     parseTypeCondition,
+	"uploader": parseUploaderCondition,
+	"workflow": parseWorkflowCondition,
+	"taken":    parseTakenCondition,
+	"size":     parseSizeCondition,
+	"starred":  boolCondition("materials.is_starred"),
+	"public":   boolCondition("materials.is_public"),
+}
+
+// splitQueryTerms 按空白切分查询，双引号内的空白不切分
+func splitQueryTerms(q string) ([]queryTerm, error) {
+	var terms []queryTerm
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		inQuote := false
+		var value strings.Builder
+		for ; i < len(runes) && (inQuote || !unicode.IsSpace(runes[i])); i++ {
+			if runes[i] == '"' {
+				inQuote = !inQuote
+				continue
+			}
+			value.WriteRune(runes[i])
+		}
+		raw := string(runes[start:i])
+		if inQuote {
+			return nil, &QueryError{Position: start + 1, Token: raw, Message: "缺少右引号"}
+		}
+		terms = append(terms, queryTerm{raw: raw, position: start + 1, value: value.String()})
+	}
+	return terms, nil
+}
+
+// ParseMaterialQuery 解析查询语言
+func ParseMaterialQuery(q string) (*MaterialQuery, error) {
+	terms, err := splitQueryTerms(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MaterialQuery{}
+	var keywords []string
+	for _, term := range terms {
+		body := term.value
+		if len(body) > 1 && strings.HasPrefix(body, "-") {
+			term.negate = true
+			body = body[1:]
+		}
+		if idx := strings.Index(body, ":"); idx > 0 && queryFieldPattern.MatchString(body[:idx]) {
+			term.field = body[:idx]
+			term.value = body[idx+1:]
+		} else {
+			term.value = body
+		}
+
+		if term.field == "" {
+			if term.negate {
+				result.ExcludedWords = append(result.ExcludedWords, term.value)
+			} else {
+				keywords = append(keywords, term.value)
+			}
+			continue
+		}
+
+		parser, ok := queryFieldParsers[term.field]
+		if !ok {
+			return nil, &QueryError{Position: term.position, Token: term.raw, Message: "未知的筛选字段 " + term.field}
+		}
+		if term.value == "" {
+			return nil, &QueryError{Position: term.position, Token: term.raw, Message: "缺少筛选值"}
+		}
+		sql, args, err := parser(term.value)
+		if err != nil {
+			return nil, &QueryError{Position: term.position, Token: term.raw, Message: err.Error()}
+		}
+		result.conditions = append(result.conditions, queryCondition{negate: term.negate, sql: sql, args: args})
+	}
+	result.Keyword = strings.Join(keywords, " ")
+	return result, nil
+}
+
+// WithParsedQuery 应用查询语言中的筛选条件；全文检索关键词需由调用方通过 WithKeyword 应用
+func (qb *MaterialQueryBuilder) WithParsedQuery(mq *MaterialQuery) *MaterialQueryBuilder {
+	for _, cond := range mq.conditions {
+		if cond.negate {
+			// 取反时将 NULL 视为不匹配，避免缺失值的素材被一并排除
+			qb.query = qb.query.Where("NOT COALESCE(("+cond.sql+"), FALSE)", cond.args...)
+		} else {
+			qb.query = qb.query.Where(cond.sql, cond.args...)
+		}
+	}
+	for _, word := range mq.ExcludedWords {
+		if tsquery := services.BuildTSQuery(word); tsquery != "" {
+			qb.query = qb.query.Where("materials.id NOT IN (SELECT material_id FROM material_searches WHERE tokens @@ CAST(? AS tsquery))", tsquery)
+		}
+	}
+	return qb
+}
+
+// WithTakenRange 按拍摄时间范围筛选（无拍摄时间时按上传时间），日期格式同查询语言的 taken 字段
+func (qb *MaterialQueryBuilder) WithTakenRange(from, to string) error {
+	if from == "" && to == "" {
+		return nil
+	}
+	sql, args, err := parseTakenCondition(from + ".." + to)
+	if err != nil {
+		return err
+	}
+	qb.query = qb.query.Where(sql, args...)
+	return nil
+}
+
+// escapeLike 转义 LIKE 模式中的特殊字符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func parseTagCondition(value string) (string, []interface{}, error) {
+	return "EXISTS (SELECT 1 FROM material_tags qmt JOIN tags qt ON qt.id = qmt.tag_id WHERE qmt.material_id = materials.id AND qt.name = ?)",
+		[]interface{}{value}, nil
+}
+
+func parseTypeCondition(value string) (string, []interface{}, error) {
+	switch value {
+	case "image", "video":
+		return "materials.file_type = ?", []interface{}{value}, nil
+	}
+	return "", nil, fmt.Errorf("type 只能是 image 或 video")
+}
+
+func parseUploaderCondition(value string) (string, []interface{}, error) {
+	return "EXISTS (SELECT 1 FROM users qu WHERE qu.id = materials.uploaded_by AND qu.username ILIKE ?)",
+		[]interface{}{escapeLike(value) + "%"}, nil
+}
+
+func parseWorkflowCondition(value string) (string, []interface{}, error) {
+	if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return "materials.workflow_id = ?", []interface{}{uint(id)}, nil
+	}
+	return "EXISTS (SELECT 1 FROM workflow_groups qw WHERE qw.id = materials.workflow_id AND qw.name ILIKE ?)",
+		[]interface{}{"%" + escapeLike(value) + "%"}, nil
+}
+
+func boolCondition(column string) func(string) (string, []interface{}, error) {
+	return func(value string) (string, []interface{}, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("只能是 true 或 false")
+		}
+		return column + " = ?", []interface{}{b}, nil
+	}
+}
+
+// splitComparison 拆分比较运算符，返回运算符与剩余部分
+func splitComparison(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "", value
+}
+
+// localTimeExpr 将 timestamptz 表达式转换为配置时区的本地时间，按日期分组时与 parseDatePeriod 使用同一时区
+func localTimeExpr(expr string) string {
+	return "(" + expr + " AT TIME ZONE '" + config.AppConfig.Server.Timezone + "')"
+}
+
+// parseDatePeriod 解析 2024、2024-09 或 2024-09-15，返回配置时区下该时间段的起止时间 [start, end)
+func parseDatePeriod(value string) (start, end time.Time, err error) {
+	layouts := []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	}
+	for _, l := range layouts {
+		if t, err := time.ParseInLocation(l.layout, value, config.Location()); err == nil {
+			return t, l.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("无效的日期 %s，应为 YYYY、YYYY-MM 或 YYYY-MM-DD", value)
+}
+
+// parseTakenCondition 拍摄时间，无拍摄时间时按上传时间计算
+func parseTakenCondition(value string) (string, []interface{}, error) {
+	const column = "COALESCE(materials.taken_at, materials.upload_time)"
+
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		if from == "" && to == "" {
+			return "", nil, fmt.Errorf("日期范围至少需要一端")
+		}
+		var conds []string
+		var args []interface{}
+		if from != "" {
+			start, _, err := parseDatePeriod(from)
+			if err != nil {
+				return "", nil, err
+			}
+			conds = append(conds, column+" >= ?")
+			args = append(args, start)
+		}
+		if to != "" {
+			_, end, err := parseDatePeriod(to)
+			if err != nil {
+				return "", nil, err
+			}
+			conds = append(conds, column+" < ?")
+			args = append(args, end)
+		}
+		return strings.Join(conds, " AND "), args, nil
+	}
+
+	op, rest := splitComparison(value)
+	start, end, err := parseDatePeriod(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	switch op {
+	case ">":
+		return column + " >= ?", []interface{}{end}, nil
+	case ">=":
+		return column + " >= ?", []interface{}{start}, nil
+	case "<":
+		return column + " < ?", []interface{}{start}, nil
+	case "<=":
+		return column + " < ?", []interface{}{end}, nil
+	default:
+		return column + " >= ? AND " + column + " < ?", []interface{}{start, end}, nil
+	}
+}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMG]?B?)$`)
+
+// parseSize 解析 50MB、1.5GB、2048 等大小表示
+func parseSize(value string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.ToUpper(value))
+	if m == nil {
+		return 0, fmt.Errorf("无效的大小 %s，应为数字加可选单位 KB/MB/GB", value)
+	}
+	unit := m[2]
+	if len(unit) == 1 && unit != "B" {
+		unit += "B" // 允许 50M 这样的简写
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	return int64(n * float64(sizeUnits[unit])), nil
+}
+
+func parseSizeCondition(value string) (string, []interface{}, error) {
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		min, err := parseSize(from)
+		if err != nil {
+			return "", nil, err
+		}
+		max, err := parseSize(to)
+		if err != nil {
+			return "", nil, err
+		}
+		return "materials.file_size BETWEEN ? AND ?", []interface{}{min, max}, nil
+	}
+
+	op, rest := splitComparison(value)
+	size, err := parseSize(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	if op == "" {
+		op = "="
+	}
+	return "materials.file_size " + op + " ?", []interface{}{size}, nil
+}
@@ -0,0 +1,184 @@
+package materials
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"ahsfnu-media-cloud/internal/config"
+)
+
+// withTimezone 临时设置配置的时区
+func withTimezone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{Server: config.ServerConfig{Timezone: name, Location: loc}}
+	t.Cleanup(func() { config.AppConfig = previous })
+	return loc
+}
+
+func TestParseDatePeriod(t *testing.T) {
+	loc := withTimezone(t, "Asia/Shanghai")
+	tests := []struct {
+		value   string
+		start   time.Time
+		end     time.Time
+		wantErr bool
+	}{
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, loc), time.Date(2025, 1, 1, 0, 0, 0, 0, loc), false},
+		{"2024-12", time.Date(2024, 12, 1, 0, 0, 0, 0, loc), time.Date(2025, 1, 1, 0, 0, 0, 0, loc), false},
+		{"2024-02-29", time.Date(2024, 2, 29, 0, 0, 0, 0, loc), time.Date(2024, 3, 1, 0, 0, 0, 0, loc), false},
+		{"2024-13", time.Time{}, time.Time{}, true},
+		{"2024/09", time.Time{}, time.Time{}, true},
+		{"", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, end, err := parseDatePeriod(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (!start.Equal(tt.start) || !end.Equal(tt.end)) {
+				t.Fatalf("范围为 [%v, %v)，期望 [%v, %v)", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestLocalTimeExpr(t *testing.T) {
+	withTimezone(t, "Asia/Shanghai")
+	got := localTimeExpr("materials.upload_time")
+	if want := "(materials.upload_time AT TIME ZONE 'Asia/Shanghai')"; got != want {
+		t.Fatalf("得到 %s，期望 %s", got, want)
+	}
+}
+
+func TestParseMaterialQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        string
+		keyword  string
+		excluded []string
+		negated  []bool        // 各筛选条件是否取反
+		args     []interface{} // 第一个筛选条件的参数，为空时不检查
+		errPos   int           // 期望的出错位置，0 表示不出错
+	}{
+		{"空查询", "   ", "", nil, nil, nil, 0},
+		{"只有关键词", "迎新  晚会", "迎新 晚会", nil, nil, nil, 0},
+		{"字段与关键词混合", "tag:迎新 type:video 晚会", "晚会", nil, []bool{false, false}, []interface{}{"迎新"}, 0},
+		{"引号包裹的值", `tag:"开学 典礼" 晚会`, "晚会", nil, []bool{false}, []interface{}{"开学 典礼"}, 0},
+		{"取反", "-tag:废片 -模糊 合影", "合影", []string{"模糊"}, []bool{true}, nil, 0},
+		{"单独的减号是关键词", "- 合影", "- 合影", nil, nil, nil, 0},
+		{"冒号前不是字段名", "10:30 合影", "10:30 合影", nil, nil, nil, 0},
+		{"按ID筛选工作流", "workflow:12", "", nil, []bool{false}, []interface{}{uint(12)}, 0},
+		{"上传者前缀匹配并转义", "uploader:zhang_", "", nil, []bool{false}, []interface{}{`zhang\_%`}, 0},
+		{"未知字段", "晚会 color:red", "", nil, nil, nil, 4},
+		{"缺少筛选值", "tag:", "", nil, nil, nil, 1},
+		{"缺少右引号", `合影 tag:"开学`, "", nil, nil, nil, 4},
+		{"类型无效", "type:audio", "", nil, nil, nil, 1},
+		{"布尔值无效", "starred:yes", "", nil, nil, nil, 1},
+		{"大小无效", "size:big", "", nil, nil, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq, err := ParseMaterialQuery(tt.q)
+			if tt.errPos != 0 {
+				var qe *QueryError
+				if !errors.As(err, &qe) {
+					t.Fatalf("应返回 QueryError，得到 %v", err)
+				}
+				if qe.Position != tt.errPos {
+					t.Fatalf("出错位置为 %d，期望 %d", qe.Position, tt.errPos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("返回错误: %v", err)
+			}
+			if mq.Keyword != tt.keyword {
+				t.Fatalf("关键词为 %q，期望 %q", mq.Keyword, tt.keyword)
+			}
+			if !reflect.DeepEqual(mq.ExcludedWords, tt.excluded) {
+				t.Fatalf("排除词为 %v，期望 %v", mq.ExcludedWords, tt.excluded)
+			}
+			var negated []bool
+			for _, cond := range mq.conditions {
+				negated = append(negated, cond.negate)
+			}
+			if !reflect.DeepEqual(negated, tt.negated) {
+				t.Fatalf("筛选条件取反情况为 %v，期望 %v", negated, tt.negated)
+			}
+			if tt.args != nil && !reflect.DeepEqual(mq.conditions[0].args, tt.args) {
+				t.Fatalf("参数为 %#v，期望 %#v", mq.conditions[0].args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseSizeCondition(t *testing.T) {
+	tests := []struct {
+		value   string
+		sql     string
+		args    []interface{}
+		wantErr bool
+	}{
+		{"2048", "materials.file_size = ?", []interface{}{int64(2048)}, false},
+		{">50MB", "materials.file_size > ?", []interface{}{int64(50 << 20)}, false},
+		{"<=1.5g", "materials.file_size <= ?", []interface{}{int64(3 << 29)}, false},
+		{"10k..2M", "materials.file_size BETWEEN ? AND ?", []interface{}{int64(10 << 10), int64(2 << 20)}, false},
+		{"50XB", "", nil, true},
+		{"1MB..", "", nil, true},
+		{">", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			sql, args, err := parseSizeCondition(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (sql != tt.sql || !reflect.DeepEqual(args, tt.args)) {
+				t.Fatalf("得到 %s %v，期望 %s %v", sql, args, tt.sql, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseTakenCondition(t *testing.T) {
+	loc := withTimezone(t, "Asia/Shanghai")
+	const column = "COALESCE(materials.taken_at, materials.upload_time)"
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+	tests := []struct {
+		value   string
+		sql     string
+		args    []interface{}
+		wantErr bool
+	}{
+		{"2024-09", column + " >= ? AND " + column + " < ?", []interface{}{day(2024, 9, 1), day(2024, 10, 1)}, false},
+		{">2024", column + " >= ?", []interface{}{day(2025, 1, 1)}, false},
+		{">=2024-09-15", column + " >= ?", []interface{}{day(2024, 9, 15)}, false},
+		{"<2024-09", column + " < ?", []interface{}{day(2024, 9, 1)}, false},
+		{"<=2024-09-15", column + " < ?", []interface{}{day(2024, 9, 16)}, false},
+		{"2024-09..2024-10", column + " >= ? AND " + column + " < ?", []interface{}{day(2024, 9, 1), day(2024, 11, 1)}, false},
+		{"..2024", column + " < ?", []interface{}{day(2025, 1, 1)}, false},
+		{"2024-09..", column + " >= ?", []interface{}{day(2024, 9, 1)}, false},
+		{"..", "", nil, true},
+		{"2024-9", "", nil, true},
+		{"2024..09-01", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			sql, args, err := parseTakenCondition(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (sql != tt.sql || !reflect.DeepEqual(args, tt.args)) {
+				t.Fatalf("得到 %s %v，期望 %s %v", sql, args, tt.sql, tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package materials
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReviewStatusCount 某个审核状态下的素材数量
+type ReviewStatusCount struct {
+	Status string `json:"status"`
+	Label  string `json:"label"`
+	Count  int64  `json:"count"`
+}
+
+// WithReviewStatus 按审核状态筛选，none 表示尚未提交审核
+func (qb *MaterialQueryBuilder) WithReviewStatus(status string) *MaterialQueryBuilder {
+	switch {
+	case status == "none":
+		qb.query = qb.query.Where("materials.review_status = ''")
+	case status != "":
+		qb.query = qb.query.Where("materials.review_status = ?", status)
+	}
+	return qb
+}
+
+// reviewStatusLabel 审核状态的名称，未提交时为“未提交”
+func reviewStatusLabel(status string) string {
+	if status == "" {
+		return "未提交"
+	}
+	return models.ReviewStatusLabels[status]
+}
+
+// notifyReviewed 通知上传者素材的审核结果
+func notifyReviewed(db *gorm.DB, material *models.Material, status, reason string, actorID uint) {
+	var title string
+	switch status {
+	case models.ReviewStatusApproved:
+		title = "你的素材「" + material.OriginalFilename + "」已通过审核"
+	case models.ReviewStatusRejected:
+		title = "你的素材「" + material.OriginalFilename + "」被退回"
+	case models.ReviewStatusPublished:
+		title = "你的素材「" + material.OriginalFilename + "」已发布"
+	default:
+		return
+	}
+	err := services.Notify(db, &models.Notification{
+		UserID:     material.UploadedBy,
+		Type:       models.NotificationMaterialReviewed,
+		Title:      title,
+		Content:    reason,
+		ActorID:    &actorID,
+		MaterialID: &material.ID,
+		WorkflowID: material.WorkflowID,
+	})
+	if err != nil {
+		log.Printf("发送审核结果通知失败: %v", err)
+	}
+}
+
+// ReviewMaterial 修改工作流素材的审核状态。上传者可以提交或在退回后重新提交，
+// 其余流转只能由该工作流的审核人员或管理员操作；退回时必须填写原因，发布时素材同时设为公开
+func ReviewMaterial(c *gin.Context) {
+	service := GetMaterialService()
+
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return
+	}
+	var material models.Material
+	if err := service.db.First(&material, materialID).Error; err != nil {
+		errorResponse(c, http.StatusNotFound, "素材不存在")
+		return
+	}
+	if material.WorkflowID == nil {
+		errorResponse(c, http.StatusBadRequest, "只有工作流中的素材需要审核")
+		return
+	}
+	if !checkWorkflowWritable(c, service, material.WorkflowID) {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if _, ok := models.ReviewStatusLabels[req.Status]; !ok {
+		errorResponse(c, http.StatusBadRequest, "未知的审核状态 "+req.Status)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	isReviewer := services.HasWorkflowPermission(service.db, *material.WorkflowID, userID.(uint), userRole.(string), models.WorkflowPermReview)
+	isSubmitter := material.UploadedBy == userID.(uint) && req.Status == models.ReviewStatusSubmitted
+	if !isReviewer && !isSubmitter {
+		errorResponse(c, http.StatusForbidden, "只有该工作流的审核人员或管理员可以修改审核状态")
+		return
+	}
+	if !services.ReviewTransitionAllowed(material.ReviewStatus, req.Status) {
+		errorResponse(c, http.StatusBadRequest, "不能从「"+reviewStatusLabel(material.ReviewStatus)+"」变为「"+reviewStatusLabel(req.Status)+"」")
+		return
+	}
+	if req.Status == models.ReviewStatusRejected && req.Reason == "" {
+		errorResponse(c, http.StatusBadRequest, "退回时必须填写原因")
+		return
+	}
+
+	// 发布即公开，撤回发布时取消公开
+	var extra map[string]interface{}
+	switch {
+	case req.Status == models.ReviewStatusPublished:
+		extra = map[string]interface{}{"is_public": true}
+	case material.ReviewStatus == models.ReviewStatusPublished:
+		extra = map[string]interface{}{"is_public": false}
+	}
+	if err := services.ChangeReviewStatus(service.db, &material, req.Status, req.Reason, userID.(uint), extra); err != nil {
+		if errors.Is(err, services.ErrReviewStatusChanged) {
+			errorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, "修改审核状态失败")
+		return
+	}
+
+	updated, _ := getMaterialByID(service, materialID)
+	if extra != nil {
+		// 公开状态变化后同步公开副本
+		if err := service.uploadService.ApplyMetadataPolicy(service.db, updated); err != nil {
+			log.Printf("同步素材 %d 公开副本失败: %v", materialID, err)
+		}
+	}
+	notifyReviewed(service.db, updated, req.Status, req.Reason, userID.(uint))
+
+	successResponse(c, MaterialResponses([]models.Material{*updated})[0])
+}
+
+// GetMaterialReviewHistory 获取素材的审核记录及当前可以流转到的状态
+func GetMaterialReviewHistory(c *gin.Context) {
+	service := GetMaterialService()
+
+	materialID, valid := validateMaterialID(c)
+	if !valid {
+		return
+	}
+	var material models.Material
+	if err := service.db.First(&material, materialID).Error; err != nil {
+		errorResponse(c, http.StatusNotFound, "素材不存在")
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if !canViewMaterial(service.db, &material, userID.(uint), userRole.(string)) {
+		errorResponse(c, http.StatusForbidden, "没有权限查看此素材")
+		return
+	}
+
+	var logs []models.MaterialReviewLog
+	err := service.db.Preload("Actor").Where("material_id = ?", materialID).
+		Order("created_at ASC, id ASC").Find(&logs).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取审核记录失败")
+		return
+	}
+	history := []models.MaterialReviewLogResponse{}
+	for i := range logs {
+		history = append(history, *logs[i].ToMaterialReviewLogResponse())
+	}
+
+	next := []string{}
+	if material.WorkflowID != nil {
+		isReviewer := services.HasWorkflowPermission(service.db, *material.WorkflowID, userID.(uint), userRole.(string), models.WorkflowPermReview)
+		for _, status := range services.NextReviewStatuses(material.ReviewStatus) {
+			if isReviewer || (material.UploadedBy == userID.(uint) && status == models.ReviewStatusSubmitted) {
+				next = append(next, status)
+			}
+		}
+	}
+	successResponse(c, gin.H{
+		"review_status": material.ReviewStatus,
+		"next_statuses": next,
+		"history":       history,
+	})
+}
+
+// workflowReviewScope 获取工作流及当前用户是否拥有审核权限
+func workflowReviewScope(c *gin.Context, service *MaterialService) (*models.WorkflowGroup, bool, bool) {
+	workflowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "无效的工作流ID")
+		return nil, false, false
+	}
+	var workflow models.WorkflowGroup
+	if err := service.db.First(&workflow, workflowID).Error; err != nil {
+		errorResponse(c, http.StatusNotFound, "工作流不存在")
+		return nil, false, false
+	}
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	return &workflow, services.HasWorkflowPermission(service.db, workflow.ID, userID.(uint), userRole.(string), models.WorkflowPermReview), true
+}
+
+// GetWorkflowReviewSummary 获取工作流中各审核状态的素材数量
+func GetWorkflowReviewSummary(c *gin.Context) {
+	service := GetMaterialService()
+
+	workflow, canReview, ok := workflowReviewScope(c, service)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	query := service.db.Model(&models.Material{}).Where("workflow_id = ?", workflow.ID)
+	if userRole.(string) != "admin" {
+		condition, args := visibleCondition(userID.(uint))
+		query = query.Where(condition, args...)
+	}
+	var rows []struct {
+		ReviewStatus string
+		Count        int64
+	}
+	if err := query.Select("review_status, COUNT(*) AS count").Group("review_status").Scan(&rows).Error; err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取审核统计失败")
+		return
+	}
+	counts := make(map[string]int64)
+	for _, row := range rows {
+		counts[row.ReviewStatus] = row.Count
+	}
+
+	summary := []ReviewStatusCount{{Status: "none", Label: reviewStatusLabel(""), Count: counts[""]}}
+	for _, status := range models.ReviewStatuses {
+		summary = append(summary, ReviewStatusCount{Status: status, Label: reviewStatusLabel(status), Count: counts[status]})
+	}
+	successResponse(c, gin.H{
+		"workflow_id": workflow.ID,
+		"can_review":  canReview,
+		"statuses":    summary,
+	})
+}
+
+// GetWorkflowReviewMaterials 按审核状态列出工作流中的素材，先上传的排在前面
+func GetWorkflowReviewMaterials(c *gin.Context) {
+	service := GetMaterialService()
+
+	status := c.Param("status")
+	if _, ok := models.ReviewStatusLabels[status]; !ok && status != "none" {
+		errorResponse(c, http.StatusBadRequest, "未知的审核状态 "+status)
+		return
+	}
+	workflow, _, ok := workflowReviewScope(c, service)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	query := NewMaterialQueryBuilder(service.db).
+		WithWorkflow(strconv.FormatUint(uint64(workflow.ID), 10)).
+		WithReviewStatus(status).
+		WithFileType(c.Query("file_type")).
+		WithVisibleTo(userID.(uint), userRole.(string)).
+		Build()
+
+	var items []models.Material
+	var total int64
+	query.Count(&total)
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).
+		Order("materials.upload_time ASC, materials.id ASC").Find(&items).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取审核素材失败")
+		return
+	}
+	paginatedResponse(c, MaterialResponses(items), page, pageSize, total)
+}
@@ -0,0 +1,132 @@
+package materials
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 时间线粒度 -> 分组格式（与查询语言 taken 字段的日期格式一致，便于下钻）
+var timelineFormats = map[string]string{
+	"day":   "YYYY-MM-DD",
+	"month": "YYYY-MM",
+	"year":  "YYYY",
+}
+
+const (
+	// 每个分组默认返回的代表缩略图数量
+	timelineDefaultSamples = 4
+	timelineMaxSamples     = 12
+	// 拍摄时间，无拍摄时间时按上传时间
+	timelineTimeExpr = "COALESCE(materials.taken_at, materials.upload_time)"
+)
+
+// TimelineSample 分组中的代表素材
+type TimelineSample struct {
+	ID            uint   `json:"id"`
+	ThumbnailPath string `json:"thumbnail_path"`
+}
+
+// TimelineBucket 时间线中的一个分组
+type TimelineBucket struct {
+	Period    string           `json:"period"`
+	Count     int64            `json:"count"`
+	Samples   []TimelineSample `json:"samples"`
+	Drilldown string           `json:"drilldown"` // 该分组对应的搜索素材查询参数
+}
+
+// timelineDrilldown 在当前筛选参数上追加该分组的拍摄时间条件
+func timelineDrilldown(filters url.Values, period string) string {
+	params := url.Values{}
+	for _, key := range SearchMaterialFilterParams {
+		if filters.Has(key) {
+			params.Set(key, filters.Get(key))
+		}
+	}
+	params.Set("q", strings.TrimSpace(params.Get("q")+" taken:"+period))
+	return params.Encode()
+}
+
+// GetMaterialTimeline 按拍摄日期统计可见素材数量，支持与搜索素材相同的筛选参数
+func GetMaterialTimeline(c *gin.Context) {
+	service := GetMaterialService()
+	params := c.Request.URL.Query()
+
+	granularity := c.DefaultQuery("granularity", "month")
+	format, ok := timelineFormats[granularity]
+	if !ok {
+		errorResponse(c, http.StatusBadRequest, "granularity 只能是 day、month 或 year")
+		return
+	}
+	samples, err := strconv.Atoi(c.DefaultQuery("samples", strconv.Itoa(timelineDefaultSamples)))
+	if err != nil || samples < 0 || samples > timelineMaxSamples {
+		errorResponse(c, http.StatusBadRequest, "samples 应为 0 到 12 之间的整数")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	queryBuilder, _, err := buildSearchQuery(service.db, params, userID.(uint), userRole.(string))
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	ids := queryBuilder.Build().Select("materials.id")
+
+	periodExpr := "TO_CHAR(" + localTimeExpr(timelineTimeExpr) + ", '" + format + "')"
+	buckets := []TimelineBucket{}
+	err = service.db.Model(&models.Material{}).
+		Select(periodExpr+" AS period, COUNT(*) AS count").
+		Where("materials.id IN (?)", ids).
+		Group("period").Order("period DESC").
+		Scan(&buckets).Error
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, "获取时间线失败")
+		return
+	}
+
+	// 每个分组挑选收藏优先、清晰度高的素材作为代表
+	byPeriod := make(map[string][]TimelineSample)
+	if samples > 0 && len(buckets) > 0 {
+		var rows []struct {
+			Period string
+			models.Material
+		}
+		ranked := service.db.Model(&models.Material{}).
+			Select("materials.*, "+periodExpr+" AS period, ROW_NUMBER() OVER (PARTITION BY "+periodExpr+
+				" ORDER BY materials.is_starred DESC, materials.sharpness_score DESC NULLS LAST, materials.id DESC) AS sample_rank").
+			Where("materials.id IN (?) AND materials.thumbnail_path <> ''", ids)
+		err = service.db.Table("(?) AS ranked", ranked).
+			Where("sample_rank <= ?", samples).
+			Order("period DESC, sample_rank").
+			Scan(&rows).Error
+		if err != nil {
+			errorResponse(c, http.StatusInternalServerError, "获取时间线失败")
+			return
+		}
+		for i := range rows {
+			byPeriod[rows[i].Period] = append(byPeriod[rows[i].Period], TimelineSample{
+				ID:            rows[i].ID,
+				ThumbnailPath: service.uploadService.GetThumbnailURL(&rows[i].Material),
+			})
+		}
+	}
+
+	for i := range buckets {
+		buckets[i].Samples = byPeriod[buckets[i].Period]
+		if buckets[i].Samples == nil {
+			buckets[i].Samples = []TimelineSample{}
+		}
+		buckets[i].Drilldown = timelineDrilldown(params, buckets[i].Period)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        buckets,
+		"granularity": granularity,
+	})
+}
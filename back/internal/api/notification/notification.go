@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		db: database.GetDB(),
+	}
+}
+
+// NotificationPreferenceItem 某类通知的开关状态
+type NotificationPreferenceItem struct {
+	Type    string `json:"type"`
+	Label   string `json:"label"`
+	Enabled bool   `json:"enabled"`
+}
+
+// unreadCounts 按类型统计未读通知数量
+func (s *NotificationService) unreadCounts(userID uint) (int64, map[string]int64, error) {
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	err := s.db.Model(&models.Notification{}).
+		Select("type, COUNT(*) AS count").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Group("type").Scan(&rows).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	var total int64
+	byType := make(map[string]int64)
+	for _, row := range rows {
+		byType[row.Type] = row.Count
+		total += row.Count
+	}
+	return total, byType, nil
+}
+
+// GetNotifications 获取当前用户的通知，可按未读与类型筛选
+func GetNotifications(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := service.db.Model(&models.Notification{}).Where("user_id = ?", userID.(uint))
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	var total int64
+	query.Count(&total)
+	var notifications []models.Notification
+	err := query.Preload("Actor").Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+	unread, _, err := service.unreadCounts(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+
+	responses := []models.NotificationResponse{}
+	for i := range notifications {
+		responses = append(responses, *notifications[i].ToNotificationResponse())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":         responses,
+		"unread_count": unread,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// GetUnreadCount 获取未读通知数量，含按类型的统计
+func GetUnreadCount(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	total, byType, err := service.unreadCounts(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取未读数量失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread_count": total, "by_type": byType})
+}
+
+// MarkNotificationRead 将一条通知标记为已读
+func MarkNotificationRead(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通知ID"})
+		return
+	}
+	var notification models.Notification
+	if err := service.db.Where("user_id = ?", userID.(uint)).First(&notification, notificationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+	if notification.ReadAt == nil {
+		if err := service.db.Model(&notification).Update("read_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已标记为已读"})
+}
+
+// MarkAllNotificationsRead 将全部（或指定类型的）未读通知标记为已读
+func MarkAllNotificationsRead(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	query := service.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID.(uint))
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "updated": result.RowsAffected})
+}
+
+// preferenceItems 当前用户的通知偏好，包含所有通知类型
+func (s *NotificationService) preferenceItems(userID uint) ([]NotificationPreferenceItem, error) {
+	var preferences []models.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool)
+	for _, preference := range preferences {
+		enabled[preference.Type] = preference.Enabled
+	}
+
+	items := []NotificationPreferenceItem{}
+	for notificationType, label := range models.NotificationTypeLabels {
+		item := NotificationPreferenceItem{Type: notificationType, Label: label, Enabled: true}
+		if value, ok := enabled[notificationType]; ok {
+			item.Enabled = value
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Type < items[j].Type })
+	return items, nil
+}
+
+// GetNotificationPreferences 获取各类通知的开关
+func GetNotificationPreferences(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	items, err := service.preferenceItems(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知设置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// UpdateNotificationPreferences 设置各类通知的开关，请求体为 {类型: 是否开启}
+func UpdateNotificationPreferences(c *gin.Context) {
+	service := NewNotificationService()
+	userID, _ := c.Get("user_id")
+
+	var req map[string]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for notificationType := range req {
+		if _, ok := models.NotificationTypeLabels[notificationType]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未知的通知类型 " + notificationType})
+			return
+		}
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		for notificationType, enabled := range req {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+			}).Create(&models.NotificationPreference{UserID: userID.(uint), Type: notificationType, Enabled: enabled}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存通知设置失败"})
+		return
+	}
+
+	items, _ := service.preferenceItems(userID.(uint))
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
@@ -0,0 +1,215 @@
+package place
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PlaceService struct {
+	db *gorm.DB
+}
+
+func NewPlaceService() *PlaceService {
+	return &PlaceService{
+		db: database.GetDB(),
+	}
+}
+
+// requireAdmin 地点只能由管理员维护
+func requireAdmin(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	if role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员可以管理地点"})
+		return false
+	}
+	return true
+}
+
+// retagAsync 在后台重新标记地点内的素材，并同步检索索引
+func (s *PlaceService) retagAsync(place models.Place) {
+	go func() {
+		ids, err := services.RetagPlace(s.db, &place)
+		if err != nil {
+			log.Printf("重新标记地点 %d 失败: %v", place.ID, err)
+			return
+		}
+		if len(ids) > 0 {
+			if err := services.ReindexMaterials(s.db, "id IN ?", ids); err != nil {
+				log.Printf("重建检索索引失败: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *PlaceService) getPlace(c *gin.Context) (*models.Place, bool) {
+	placeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的地点ID"})
+		return nil, false
+	}
+	var place models.Place
+	if err := s.db.First(&place, placeID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "地点不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取地点失败"})
+		return nil, false
+	}
+	return &place, true
+}
+
+// GetPlaces 获取命名地点列表
+func GetPlaces(c *gin.Context) {
+	service := NewPlaceService()
+
+	var places []models.Place
+	if err := service.db.Order("name").Find(&places).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取地点列表失败"})
+		return
+	}
+
+	responses := []models.PlaceResponse{}
+	for i := range places {
+		responses = append(responses, *places[i].ToPlaceResponse())
+	}
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// CreatePlace 创建命名地点（仅管理员），并在后台标记落在其中的素材
+func CreatePlace(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	service := NewPlaceService()
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Name        string       `json:"name" binding:"required"`
+		Description string       `json:"description"`
+		Polygon     [][2]float64 `json:"polygon" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	service.db.Model(&models.Place{}).Where("name = ?", req.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "地点名称已存在"})
+		return
+	}
+
+	place := models.Place{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   userID.(uint),
+	}
+	if err := services.SetPlacePolygon(&place, req.Polygon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.db.Create(&place).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建地点失败"})
+		return
+	}
+
+	service.retagAsync(place)
+	c.JSON(http.StatusCreated, place.ToPlaceResponse())
+}
+
+// UpdatePlace 更新命名地点（仅管理员），名称或范围变化时重新标记素材
+func UpdatePlace(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	service := NewPlaceService()
+
+	place, ok := service.getPlace(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        string       `json:"name"`
+		Description *string      `json:"description"`
+		Polygon     [][2]float64 `json:"polygon"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changed := false
+	if req.Name != "" && req.Name != place.Name {
+		var count int64
+		service.db.Model(&models.Place{}).Where("name = ? AND id <> ?", req.Name, place.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "地点名称已存在"})
+			return
+		}
+		place.Name = req.Name
+		changed = true
+	}
+	if req.Description != nil {
+		place.Description = *req.Description
+	}
+	if req.Polygon != nil {
+		if err := services.SetPlacePolygon(place, req.Polygon); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changed = true
+	}
+
+	if err := service.db.Save(place).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新地点失败"})
+		return
+	}
+
+	if changed {
+		service.retagAsync(*place)
+	}
+	c.JSON(http.StatusOK, place.ToPlaceResponse())
+}
+
+// DeletePlace 删除命名地点（仅管理员）
+func DeletePlace(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	service := NewPlaceService()
+
+	place, ok := service.getPlace(c)
+	if !ok {
+		return
+	}
+
+	var materialIDs []uint
+	service.db.Model(&models.MaterialPlace{}).Where("place_id = ?", place.ID).Pluck("material_id", &materialIDs)
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.MaterialPlace{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(place).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除地点失败"})
+		return
+	}
+
+	if len(materialIDs) > 0 {
+		services.ReindexMaterialsAsync(service.db, "id IN ?", materialIDs)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "地点删除成功"})
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"ahsfnu-media-cloud/internal/api/album"
 	"ahsfnu-media-cloud/internal/api/auth"
+	"ahsfnu-media-cloud/internal/api/embed"
+	"ahsfnu-media-cloud/internal/api/gallery"
 	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/api/notification"
+	"ahsfnu-media-cloud/internal/api/place"
+	"ahsfnu-media-cloud/internal/api/share"
 	"ahsfnu-media-cloud/internal/api/tag"
 	"ahsfnu-media-cloud/internal/api/workflow"
-	"ahsfnu-media-cloud/internal/config"
 	"ahsfnu-media-cloud/internal/database"
 	"ahsfnu-media-cloud/internal/middleware"
 
@@ -17,8 +22,12 @@ func SetupRoutes(r *gin.Engine) {
 	// 添加中间件
 	r.Use(middleware.CORSMiddleware())
 
-	// 静态文件服务 - 提供文件的访问
-	r.Static("/uploads", config.AppConfig.Upload.UploadPath)
+	// 文件访问 - 缩略图与公开副本直接提供，原始文件需满足公开访问条件
+	r.GET("/uploads/*filepath", materials.ServeUpload)
+	r.HEAD("/uploads/*filepath", materials.ServeUpload)
+
+	// 公开素材嵌入页
+	r.GET("/embed/:id", embed.EmbedPage)
 
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
@@ -32,6 +41,32 @@ func SetupRoutes(r *gin.Engine) {
 			authGroup.POST("/login", auth.Login)
 			authGroup.POST("/register", auth.Register)
 		}
+
+		// 公开画廊：匿名访问者只能看到公开素材的公开信息，登录用户获得完整信息
+		galleryGroup := v1.Group("/gallery")
+		galleryGroup.Use(middleware.OptionalAuthMiddleware(database.GetDB()))
+		{
+			galleryGroup.GET("/materials", gallery.GetGalleryMaterials)
+			galleryGroup.GET("/materials/:id", gallery.GetGalleryMaterial)
+			galleryGroup.GET("/tags", gallery.GetGalleryTags)
+			galleryGroup.GET("/tags/:id/materials", gallery.GetGalleryTagMaterials)
+			galleryGroup.GET("/workflows", gallery.GetGalleryWorkflows)
+			galleryGroup.GET("/workflows/:id", gallery.GetGalleryWorkflow)
+		}
+
+		// oEmbed 提供方接口
+		v1.GET("/oembed", embed.OEmbed)
+
+		// 分享链接免登录访问
+		shareGroup := v1.Group("/share")
+		{
+			shareGroup.GET("/:token", share.GetSharedContent)
+			shareGroup.POST("/:token", share.GetSharedContent)
+			shareGroup.GET("/:token/materials/:materialId/thumbnail", share.GetSharedThumbnail)
+			shareGroup.POST("/:token/materials/:materialId/thumbnail", share.GetSharedThumbnail)
+			shareGroup.GET("/:token/materials/:materialId/download", share.DownloadSharedMaterial)
+			shareGroup.POST("/:token/materials/:materialId/download", share.DownloadSharedMaterial)
+		}
 	}
 	protected := v1.Group("/")
 	protected.Use(middleware.AuthMiddleware(database.GetDB()))
@@ -44,6 +79,19 @@ func SetupRoutes(r *gin.Engine) {
 			materialGroup.GET("/:id", materials.GetMaterial)
 			materialGroup.DELETE("/:id", materials.DeleteMaterial)
 			materialGroup.GET("", materials.SearchMaterials)
+			materialGroup.GET("/timeline", materials.GetMaterialTimeline) // 按拍摄日期统计的时间线
+			materialGroup.GET("/geojson", materials.GetMaterialsGeoJSON)  // 带拍摄位置的素材
+			materialGroup.POST("/:id/restore", materials.RestoreMaterial) // 从冷存储恢复原始文件
+			materialGroup.GET("/:id/download", materials.DownloadMaterial)
+			materialGroup.GET("/:id/processed", materials.DownloadProcessed) // 处理流程的成品文件
+			materialGroup.GET("/:id/comments", materials.GetMaterialComments)
+			materialGroup.POST("/:id/comments", materials.CreateMaterialComment)
+			materialGroup.PUT("/:id/comments/:commentId", materials.UpdateMaterialComment)
+			materialGroup.DELETE("/:id/comments/:commentId", materials.DeleteMaterialComment)
+			materialGroup.POST("/:id/comments/:commentId/resolve", materials.ResolveMaterialComment)
+			materialGroup.DELETE("/:id/comments/:commentId/resolve", materials.UnresolveMaterialComment)
+			materialGroup.POST("/:id/review", materials.ReviewMaterial) // 修改审核状态
+			materialGroup.GET("/:id/review-history", materials.GetMaterialReviewHistory)
 		}
 		protected.POST("/invite_codes", auth.GenerateInviteCodes)
 		protected.GET("/invite_codes", auth.ListInviteCodes)
@@ -74,7 +122,80 @@ func SetupRoutes(r *gin.Engine) {
 			workflowGroup.PUT("/:id", workflow.UpdateWorkflow)
 			workflowGroup.DELETE("/:id", workflow.DeleteWorkflow)
 			workflowGroup.POST("/:id/members", workflow.AddWorkflowMember)
+			workflowGroup.PUT("/:id/members/:userId", workflow.UpdateWorkflowMemberRole)
 			workflowGroup.DELETE("/:id/members/:userId", workflow.RemoveWorkflowMember)
+			workflowGroup.GET("/:id/rejectable", materials.GetRejectableMaterials) // 可能为废片的图片
+			workflowGroup.POST("/:id/archive", workflow.ArchiveWorkflow)
+			workflowGroup.POST("/:id/reopen", workflow.ReopenWorkflow)
+			workflowGroup.POST("/:id/cold-storage", workflow.MoveWorkflowToColdStorage)
+			workflowGroup.POST("/:id/pipeline/run", workflow.RunWorkflowPipeline)          // 对已有素材执行处理流程
+			workflowGroup.GET("/:id/pipeline/runs", workflow.GetWorkflowPipelineRuns)      // 处理流程执行记录
+			workflowGroup.GET("/:id/review", materials.GetWorkflowReviewSummary)           // 各审核状态的素材数量
+			workflowGroup.GET("/:id/review/:status", materials.GetWorkflowReviewMaterials) // 按审核状态列出素材
+			workflowGroup.POST("/:id/clone", workflow.CloneWorkflow)                       // 克隆工作流（不含素材）
+			workflowGroup.POST("/:id/template", workflow.SaveWorkflowAsTemplate)           // 保存为模板
+		}
+
+		// 工作流模板相关路由
+		templateGroup := protected.Group("/workflow-templates")
+		{
+			templateGroup.GET("", workflow.GetWorkflowTemplates)
+			templateGroup.POST("", workflow.CreateWorkflowTemplate)
+			templateGroup.GET("/:id", workflow.GetWorkflowTemplate)
+			templateGroup.PUT("/:id", workflow.UpdateWorkflowTemplate)
+			templateGroup.DELETE("/:id", workflow.DeleteWorkflowTemplate)
+			templateGroup.PUT("/:id/publish", workflow.PublishWorkflowTemplate)       // 发布或取消发布（仅系统管理员）
+			templateGroup.POST("/:id/workflows", workflow.CreateWorkflowFromTemplate) // 按模板创建工作流
+		}
+
+		// 命名地点相关路由
+		placeGroup := protected.Group("/places")
+		{
+			placeGroup.GET("", place.GetPlaces)
+			placeGroup.POST("", place.CreatePlace)
+			placeGroup.PUT("/:id", place.UpdatePlace)
+			placeGroup.DELETE("/:id", place.DeletePlace)
+		}
+
+		// 相册相关路由
+		albumGroup := protected.Group("/albums")
+		{
+			albumGroup.GET("", album.GetAlbums)
+			albumGroup.POST("", album.CreateAlbum)
+			albumGroup.GET("/:id", album.GetAlbum)
+			albumGroup.PUT("/:id", album.UpdateAlbum)
+			albumGroup.DELETE("/:id", album.DeleteAlbum)
+			albumGroup.GET("/:id/materials", album.GetAlbumMaterials)
+			albumGroup.POST("/:id/materials", album.AddAlbumMaterials)
+			albumGroup.DELETE("/:id/materials/:materialId", album.RemoveAlbumMaterial)
+			albumGroup.PUT("/:id/order", album.ReorderAlbumMaterials)
+		}
+
+		// 站内通知
+		notificationGroup := protected.Group("/notifications")
+		{
+			notificationGroup.GET("", notification.GetNotifications)
+			notificationGroup.GET("/unread-count", notification.GetUnreadCount)
+			notificationGroup.PUT("/read-all", notification.MarkAllNotificationsRead)
+			notificationGroup.PUT("/:id/read", notification.MarkNotificationRead)
+			notificationGroup.GET("/preferences", notification.GetNotificationPreferences)
+			notificationGroup.PUT("/preferences", notification.UpdateNotificationPreferences)
+		}
+
+		// 分享链接管理
+		protected.GET("/shares", share.GetShareLinks)
+		protected.POST("/shares", share.CreateShareLink)
+		protected.DELETE("/shares/:id", share.RevokeShareLink)
+
+		// 智能相册相关路由
+		smartAlbumGroup := protected.Group("/smart-albums")
+		{
+			smartAlbumGroup.GET("", album.GetSmartAlbums)
+			smartAlbumGroup.POST("", album.CreateSmartAlbum)
+			smartAlbumGroup.GET("/:id", album.GetSmartAlbum)
+			smartAlbumGroup.PUT("/:id", album.UpdateSmartAlbum)
+			smartAlbumGroup.DELETE("/:id", album.DeleteSmartAlbum)
+			smartAlbumGroup.GET("/:id/materials", album.GetSmartAlbumMaterials)
 		}
 
 	}
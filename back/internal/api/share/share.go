@@ -0,0 +1,450 @@
+package share
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ahsfnu-media-cloud/internal/api/materials"
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+	"ahsfnu-media-cloud/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+type ShareService struct {
+	db *gorm.DB
+}
+
+func NewShareService() *ShareService {
+	return &ShareService{
+		db: database.GetDB(),
+	}
+}
+
+type shareLinkRequest struct {
+	TargetType    string     `json:"target_type"`
+	TargetID      uint       `json:"target_id"`
+	MaterialIDs   []uint     `json:"material_ids"` // 分享工作流时选定的素材，为空表示全部
+	Password      string     `json:"password"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	AllowDownload bool       `json:"allow_download"`
+}
+
+type sharePasswordRequest struct {
+	Password string `json:"password" form:"password"`
+}
+
+// generateShareToken 生成分享链接令牌
+func generateShareToken() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// canShareTarget 检查用户能否分享目标：素材为上传者，相册为创建者或编辑者，工作流为创建者；管理员均可
+func (s *ShareService) canShareTarget(req *shareLinkRequest, userID uint, role string) (int, string) {
+	switch req.TargetType {
+	case models.ShareTargetMaterial:
+		var material models.Material
+		if err := s.db.Select("id, uploaded_by").First(&material, req.TargetID).Error; err != nil {
+			return http.StatusNotFound, "素材不存在"
+		}
+		if material.UploadedBy != userID && role != "admin" {
+			return http.StatusForbidden, "没有权限分享此素材"
+		}
+	case models.ShareTargetAlbum:
+		var album models.Album
+		if err := s.db.Preload("Collaborators").First(&album, req.TargetID).Error; err != nil {
+			return http.StatusNotFound, "相册不存在"
+		}
+		if album.CreatedBy != userID && role != "admin" && album.CollaboratorRole(userID) != models.AlbumRoleEditor {
+			return http.StatusForbidden, "没有权限分享此相册"
+		}
+	case models.ShareTargetWorkflow:
+		var workflow models.WorkflowGroup
+		if err := s.db.Select("id, created_by").First(&workflow, req.TargetID).Error; err != nil {
+			return http.StatusNotFound, "工作流不存在"
+		}
+		if !services.HasWorkflowPermission(s.db, workflow.ID, userID, role, models.WorkflowPermManage) {
+			return http.StatusForbidden, "没有权限分享此工作流"
+		}
+		if len(req.MaterialIDs) > 0 {
+			var count int64
+			s.db.Model(&models.Material{}).Where("id IN ? AND workflow_id = ?", req.MaterialIDs, req.TargetID).Count(&count)
+			if int(count) != len(uniqueIDs(req.MaterialIDs)) {
+				return http.StatusBadRequest, "选定的素材不属于此工作流"
+			}
+		}
+	default:
+		return http.StatusBadRequest, "target_type 只能是 material、album 或 workflow"
+	}
+	return 0, ""
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// sharedMaterialsQuery 分享链接包含的素材，不会超出分享者本人可见的范围
+func (s *ShareService) sharedMaterialsQuery(link *models.ShareLink) (*gorm.DB, error) {
+	var creator models.User
+	if err := s.db.Select("id, role").First(&creator, link.CreatedBy).Error; err != nil {
+		return nil, err
+	}
+	query := materials.NewMaterialQueryBuilder(s.db).WithVisibleTo(creator.ID, creator.Role).Build()
+	switch link.TargetType {
+	case models.ShareTargetMaterial:
+		query = query.Where("materials.id = ?", link.TargetID)
+	case models.ShareTargetAlbum:
+		query = query.Joins("JOIN album_items ON album_items.material_id = materials.id AND album_items.album_id = ?", link.TargetID).
+			Order("album_items.position, album_items.id")
+	case models.ShareTargetWorkflow:
+		query = query.Where("materials.workflow_id = ?", link.TargetID)
+		if ids := link.SelectedMaterialIDs(); len(ids) > 0 {
+			query = query.Where("materials.id IN ?", ids)
+		}
+		query = query.Order("materials.upload_time DESC, materials.id DESC")
+	}
+	return query, nil
+}
+
+// sharedTitle 分享内容的标题与描述
+func (s *ShareService) sharedTitle(link *models.ShareLink) (string, string, bool) {
+	switch link.TargetType {
+	case models.ShareTargetMaterial:
+		var material models.Material
+		if err := s.db.Select("id, original_filename, caption").First(&material, link.TargetID).Error; err == nil {
+			return material.OriginalFilename, material.Caption, true
+		}
+	case models.ShareTargetAlbum:
+		var album models.Album
+		if err := s.db.First(&album, link.TargetID).Error; err == nil {
+			return album.Name, album.Description, true
+		}
+	case models.ShareTargetWorkflow:
+		var workflow models.WorkflowGroup
+		if err := s.db.Select("id, name, description").First(&workflow, link.TargetID).Error; err == nil {
+			return workflow.Name, workflow.Description, true
+		}
+	}
+	return "", "", false
+}
+
+// sharePassword 读取访问密码：请求头 X-Share-Password，或 POST 请求体（JSON 或表单）中的 password
+// 不接受查询参数，避免密码出现在访问日志与浏览器历史中
+func sharePassword(c *gin.Context) string {
+	if password := c.GetHeader("X-Share-Password"); password != "" {
+		return password
+	}
+	if c.Request.Method != http.MethodPost {
+		return ""
+	}
+	var req sharePasswordRequest
+	switch c.ContentType() {
+	case binding.MIMEJSON:
+		_ = c.ShouldBindJSON(&req)
+	case binding.MIMEPOSTForm:
+		_ = c.ShouldBindWith(&req, binding.FormPost)
+	}
+	return req.Password
+}
+
+// sharedFileURL 分享中素材文件的访问地址，文件均经由分享接口提供，不暴露存储路径
+func sharedFileURL(link *models.ShareLink, materialID uint, kind string) string {
+	return fmt.Sprintf("/api/v1/share/%s/materials/%d/%s", link.Token, materialID, kind)
+}
+
+// resolveLink 按令牌获取可访问的分享链接并校验密码，失败时写入错误响应
+// 同一访问者对同一链接连续输错密码达到上限后，在一段时间内拒绝继续尝试
+func (s *ShareService) resolveLink(c *gin.Context) (*models.ShareLink, bool) {
+	var link models.ShareLink
+	if err := s.db.Where("token = ?", c.Param("token")).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享链接不存在"})
+		return nil, false
+	}
+	if link.RevokedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "分享链接已失效"})
+		return nil, false
+	}
+	if !link.IsActive() {
+		c.JSON(http.StatusGone, gin.H{"error": "分享链接已过期"})
+		return nil, false
+	}
+	if link.PasswordHash != "" {
+		key := link.Token + "|" + c.ClientIP()
+		if allowed, wait := services.SharePasswordLimiter.Allow(key, time.Now()); !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "密码错误次数过多，请稍后再试"})
+			return nil, false
+		}
+		password := sharePassword(c)
+		if password == "" || !utils.CheckPassword(password, link.PasswordHash) {
+			if password != "" {
+				services.SharePasswordLimiter.Fail(key, time.Now())
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "请输入正确的访问密码", "password_required": true})
+			return nil, false
+		}
+		services.SharePasswordLimiter.Reset(key)
+	}
+	return &link, true
+}
+
+// CreateShareLink 为素材、相册或工作流创建分享链接
+func CreateShareLink(c *gin.Context) {
+	service := NewShareService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	var req shareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TargetType != models.ShareTargetWorkflow {
+		req.MaterialIDs = nil
+	}
+	if status, message := service.canShareTarget(&req, userID.(uint), userRole.(string)); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "过期时间必须晚于当前时间"})
+		return
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成分享链接失败"})
+		return
+	}
+	link := models.ShareLink{
+		Token:         token,
+		TargetType:    req.TargetType,
+		TargetID:      req.TargetID,
+		ExpiresAt:     req.ExpiresAt,
+		AllowDownload: req.AllowDownload,
+		CreatedBy:     userID.(uint),
+	}
+	if len(req.MaterialIDs) > 0 {
+		data, _ := json.Marshal(uniqueIDs(req.MaterialIDs))
+		link.MaterialIDs = string(data)
+	}
+	if req.Password != "" {
+		if link.PasswordHash, err = utils.HashPassword(req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成分享链接失败"})
+			return
+		}
+	}
+
+	if err := service.db.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成分享链接失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, link.ToShareLinkResponse())
+}
+
+// GetShareLinks 获取自己创建的分享链接（管理员可查看全部），可按分享目标筛选
+func GetShareLinks(c *gin.Context) {
+	service := NewShareService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	query := service.db.Model(&models.ShareLink{})
+	if userRole.(string) != "admin" {
+		query = query.Where("created_by = ?", userID.(uint))
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var links []models.ShareLink
+	if err := query.Order("created_at DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享链接失败"})
+		return
+	}
+	responses := []models.ShareLinkResponse{}
+	for i := range links {
+		responses = append(responses, *links[i].ToShareLinkResponse())
+	}
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// RevokeShareLink 撤销分享链接，撤销后立即无法访问
+func RevokeShareLink(c *gin.Context) {
+	service := NewShareService()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	linkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分享链接ID"})
+		return
+	}
+	var link models.ShareLink
+	if err := service.db.First(&link, linkID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享链接不存在"})
+		return
+	}
+	if link.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限撤销此分享链接"})
+		return
+	}
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := service.db.Model(&link).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销分享链接失败"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "分享链接已撤销"})
+}
+
+// GetSharedContent 免登录查看分享内容，访问第一页时计入浏览次数
+func GetSharedContent(c *gin.Context) {
+	service := NewShareService()
+
+	link, ok := service.resolveLink(c)
+	if !ok {
+		return
+	}
+	title, description, found := service.sharedTitle(link)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享的内容已被删除"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query, err := service.sharedMaterialsQuery(link)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享的内容已被删除"})
+		return
+	}
+	var items []models.Material
+	var total int64
+	query.Count(&total)
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分享内容失败"})
+		return
+	}
+
+	if page == 1 {
+		service.db.Model(link).UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+	}
+
+	// 文件地址替换为分享接口，不允许下载时只提供缩略图
+	responses := materials.PublicMaterialResponses(items)
+	for i := range responses {
+		if responses[i].ThumbnailPath != "" {
+			responses[i].ThumbnailPath = sharedFileURL(link, responses[i].ID, "thumbnail")
+		}
+		// 加密素材同样可经分享接口下载，原始文件位于冷存储且没有公开副本时不可下载
+		downloadable := items[i].PublicPath != "" || items[i].StorageTier != services.StorageTierCold
+		if link.AllowDownload && downloadable {
+			responses[i].FilePath = sharedFileURL(link, responses[i].ID, "download")
+		} else {
+			responses[i].FilePath = ""
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"target_type":    link.TargetType,
+		"title":          title,
+		"description":    description,
+		"expires_at":     link.ExpiresAt,
+		"allow_download": link.AllowDownload,
+		"data":           responses,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// sharedMaterial 获取分享中的素材，失败时写入错误响应
+func (s *ShareService) sharedMaterial(c *gin.Context, link *models.ShareLink) (*models.Material, bool) {
+	materialID, err := strconv.ParseUint(c.Param("materialId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的素材ID"})
+		return nil, false
+	}
+
+	query, err := s.sharedMaterialsQuery(link)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享的内容已被删除"})
+		return nil, false
+	}
+	var material models.Material
+	if err := query.Where("materials.id = ?", materialID).First(&material).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "素材不在此分享中"})
+		return nil, false
+	}
+	return &material, true
+}
+
+// GetSharedThumbnail 免登录获取分享中素材的缩略图
+func GetSharedThumbnail(c *gin.Context) {
+	service := NewShareService()
+
+	link, ok := service.resolveLink(c)
+	if !ok {
+		return
+	}
+	material, ok := service.sharedMaterial(c, link)
+	if !ok {
+		return
+	}
+	materials.ServeThumbnail(c, material)
+}
+
+// DownloadSharedMaterial 免登录下载分享中的素材，计入下载次数
+// 需要去除元数据的素材只提供公开副本
+func DownloadSharedMaterial(c *gin.Context) {
+	service := NewShareService()
+
+	link, ok := service.resolveLink(c)
+	if !ok {
+		return
+	}
+	if !link.AllowDownload {
+		c.JSON(http.StatusForbidden, gin.H{"error": "此分享链接不允许下载"})
+		return
+	}
+	material, ok := service.sharedMaterial(c, link)
+	if !ok {
+		return
+	}
+
+	service.db.Model(link).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	materials.ServeShareable(c, material)
+}
@@ -6,6 +6,7 @@ import (
 
 	"ahsfnu-media-cloud/internal/database"
 	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -147,6 +148,12 @@ func UpdateTag(c *gin.Context) {
 		return
 	}
 
+	// 标签名与颜色是全局属性，已归档工作流中的素材也会显示新名称，但素材与标签的关联不变；
+	// 标签名变化后同步相关素材的检索索引
+	if updateData.Name != "" {
+		services.ReindexMaterialsAsync(service.db, "id IN (SELECT material_id FROM material_tags WHERE tag_id = ?)", tag.ID)
+	}
+
 	// 重新获取更新后的数据
 	service.db.Preload("Creator").First(&tag, tagID)
 
@@ -185,11 +192,25 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
+	// 删除标签会移除素材上的标签，已归档工作流中的素材只读，不能因此被修改
+	if services.TagUsedInArchivedWorkflows(service.db, tag.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "标签仍被已归档工作流中的素材使用，无法删除"})
+		return
+	}
+
+	// 记录受影响的素材，删除后同步检索索引
+	var materialIDs []uint
+	service.db.Model(&models.MaterialTag{}).Where("tag_id = ?", tag.ID).Pluck("material_id", &materialIDs)
+
 	// 删除标签（会级联删除关联的素材标签）
 	if err := service.db.Delete(&tag).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
 		return
 	}
 
+	if len(materialIDs) > 0 {
+		services.ReindexMaterialsAsync(service.db, "id IN ?", materialIDs)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "标签删除成功"})
 }
@@ -0,0 +1,406 @@
+package workflow
+
+import (
+	"ahsfnu-media-cloud/internal/database"
+	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// templateRequest 创建或修改模板的请求，修改时未传入的字段保持不变
+type templateRequest struct {
+	Name          *string                          `json:"name"`
+	NamePattern   *string                          `json:"name_pattern"`
+	Description   *string                          `json:"description"`
+	Type          *string                          `json:"type"`
+	Color         *string                          `json:"color"`
+	Config        *string                          `json:"config"`
+	StripMetadata *bool                            `json:"strip_metadata"`
+	CustomFields  *[]models.CustomFieldDefinition  `json:"custom_fields"`
+	DefaultTagIDs *[]uint                          `json:"default_tag_ids"`
+	Members       *[]models.WorkflowTemplateMember `json:"members"`
+}
+
+// instantiateRequest 按模板创建或克隆工作流的请求，名称为空时按名称模板生成
+type instantiateRequest struct {
+	Name     string     `json:"name"`
+	Deadline *time.Time `json:"deadline"`
+}
+
+// applyTemplateRequest 校验请求并写入模板
+func applyTemplateRequest(c *gin.Context, db *gorm.DB, template *models.WorkflowTemplate, req *templateRequest) bool {
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.NamePattern != nil {
+		template.NamePattern = *req.NamePattern
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Type != nil {
+		template.Type = *req.Type
+	}
+	if req.Color != nil {
+		template.Color = *req.Color
+	}
+	if req.Config != nil {
+		template.Config = *req.Config
+	}
+	if req.StripMetadata != nil {
+		template.StripMetadata = *req.StripMetadata
+	}
+	if template.Name == "" {
+		c.JSON(400, gin.H{"error": "模板名称不能为空"})
+		return false
+	}
+	if template.NamePattern == "" {
+		template.NamePattern = template.Name
+	}
+	if template.Type == "" {
+		template.Type = models.WorkflowTypeCustom
+	}
+	if template.Color == "" {
+		template.Color = "#409EFF"
+	}
+	config, ok := validPipelineConfig(c, db, template.Type, template.Config)
+	if !ok {
+		return false
+	}
+	template.Config = config
+
+	if req.CustomFields != nil {
+		if err := services.ValidateCustomFieldSchema(*req.CustomFields); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return false
+		}
+		template.CustomFieldSchema = ""
+		if len(*req.CustomFields) > 0 {
+			schema, _ := json.Marshal(*req.CustomFields)
+			template.CustomFieldSchema = string(schema)
+		}
+	}
+	if req.DefaultTagIDs != nil {
+		if err := services.ValidateTagIDs(db, *req.DefaultTagIDs); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return false
+		}
+		template.DefaultTags = ""
+		if len(*req.DefaultTagIDs) > 0 {
+			tags, _ := json.Marshal(*req.DefaultTagIDs)
+			template.DefaultTags = string(tags)
+		}
+	}
+	if req.Members != nil {
+		members, err := services.ValidateTemplateMembers(db, *req.Members)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return false
+		}
+		data, _ := json.Marshal(members)
+		template.Members = string(data)
+	}
+	return true
+}
+
+// findTemplate 获取当前用户可以使用的模板：自己创建的、已发布的，系统管理员可使用全部模板
+func findTemplate(c *gin.Context, db *gorm.DB) (*models.WorkflowTemplate, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	var template models.WorkflowTemplate
+	if err := db.Preload("Creator").First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(404, gin.H{"error": "模板不存在"})
+		return nil, false
+	}
+	if template.CreatedBy != userID.(uint) && !template.IsPublished && userRole.(string) != "admin" {
+		c.JSON(404, gin.H{"error": "模板不存在"})
+		return nil, false
+	}
+	return &template, true
+}
+
+// canEditTemplate 模板只能由创建者或系统管理员修改
+func canEditTemplate(c *gin.Context, template *models.WorkflowTemplate) bool {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if template.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return false
+	}
+	return true
+}
+
+// instantiateWorkflow 按模板创建工作流，通知被添加的成员并返回新工作流；
+// 请求未指定名称时使用 defaultName，defaultName 也为空时按名称模板生成
+func instantiateWorkflow(c *gin.Context, db *gorm.DB, template *models.WorkflowTemplate, defaultName string) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	var req instantiateRequest
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !validDeadline(c, req.Deadline) {
+		return
+	}
+	if req.Name == "" {
+		req.Name = defaultName
+	}
+
+	workflow, memberIDs, err := services.CreateWorkflowFromTemplate(db, template, req.Name, req.Deadline, userID.(uint), userRole.(string))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "创建工作流失败: " + err.Error()})
+		return
+	}
+	for _, memberID := range memberIDs {
+		notifyMemberAdded(db, workflow, memberID, userID.(uint))
+	}
+
+	db.Preload("Creator").Preload("Members.User").First(workflow, workflow.ID)
+	c.JSON(201, workflowResponse(c, workflow))
+}
+
+// 获取模板列表：自己创建的与已发布的模板，系统管理员可见全部
+// 可用 scope 参数只看自己创建的（mine）或已发布的（published）模板
+func GetWorkflowTemplates(c *gin.Context) {
+	db := database.GetDB()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := db.Model(&models.WorkflowTemplate{})
+	if userRole.(string) != "admin" {
+		query = query.Where("created_by = ? OR is_published = ?", userID.(uint), true)
+	}
+	switch c.Query("scope") {
+	case "mine":
+		query = query.Where("created_by = ?", userID.(uint))
+	case "published":
+		query = query.Where("is_published = ?", true)
+	case "":
+	default:
+		c.JSON(400, gin.H{"error": "无效的模板范围"})
+		return
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("name ILIKE ?", "%"+keyword+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+	var templates []models.WorkflowTemplate
+	err := query.Preload("Creator").Order("is_published DESC, updated_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&templates).Error
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取模板列表失败"})
+		return
+	}
+	responses := []models.WorkflowTemplateResponse{}
+	for i := range templates {
+		responses = append(responses, *templates[i].ToWorkflowTemplateResponse())
+	}
+
+	c.JSON(200, gin.H{
+		"data": responses,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// 获取模板详情
+func GetWorkflowTemplate(c *gin.Context) {
+	template, ok := findTemplate(c, database.GetDB())
+	if !ok {
+		return
+	}
+	c.JSON(200, template.ToWorkflowTemplateResponse())
+}
+
+// 创建模板，新模板仅创建者可见
+func CreateWorkflowTemplate(c *gin.Context) {
+	db := database.GetDB()
+	userID, _ := c.Get("user_id")
+
+	var req templateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	template := models.WorkflowTemplate{CreatedBy: userID.(uint)}
+	if !applyTemplateRequest(c, db, &template, &req) {
+		return
+	}
+	if err := db.Create(&template).Error; err != nil {
+		c.JSON(500, gin.H{"error": "创建模板失败"})
+		return
+	}
+
+	db.Preload("Creator").First(&template, template.ID)
+	c.JSON(201, template.ToWorkflowTemplateResponse())
+}
+
+// 将工作流的设置、成员角色与默认标签保存为模板（不包含素材）
+func SaveWorkflowAsTemplate(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	var workflow models.WorkflowGroup
+	if err := db.Preload("Members").First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		NamePattern string `json:"name_pattern"`
+	}
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	template := services.TemplateFromWorkflow(&workflow)
+	template.CreatedBy = userID.(uint)
+	// 处理流程引用的水印素材需是保存模板的用户可以查看的
+	if _, ok := validPipelineConfig(c, db, template.Type, template.Config); !ok {
+		return
+	}
+	if req.Name != "" {
+		template.Name = req.Name
+	}
+	if req.NamePattern != "" {
+		template.NamePattern = req.NamePattern
+	}
+	if err := db.Create(template).Error; err != nil {
+		c.JSON(500, gin.H{"error": "保存模板失败"})
+		return
+	}
+
+	db.Preload("Creator").First(template, template.ID)
+	c.JSON(201, template.ToWorkflowTemplateResponse())
+}
+
+// 修改模板
+func UpdateWorkflowTemplate(c *gin.Context) {
+	db := database.GetDB()
+	template, ok := findTemplate(c, db)
+	if !ok || !canEditTemplate(c, template) {
+		return
+	}
+
+	var req templateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyTemplateRequest(c, db, template, &req) {
+		return
+	}
+	if err := db.Omit("Creator").Save(template).Error; err != nil {
+		c.JSON(500, gin.H{"error": "更新模板失败"})
+		return
+	}
+	c.JSON(200, template.ToWorkflowTemplateResponse())
+}
+
+// 删除模板，已按模板创建的工作流不受影响
+func DeleteWorkflowTemplate(c *gin.Context) {
+	db := database.GetDB()
+	template, ok := findTemplate(c, db)
+	if !ok || !canEditTemplate(c, template) {
+		return
+	}
+	if err := db.Delete(&models.WorkflowTemplate{}, template.ID).Error; err != nil {
+		c.JSON(500, gin.H{"error": "删除模板失败"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "模板已删除"})
+}
+
+// 发布或取消发布模板（仅系统管理员），发布后所有用户都可以使用
+func PublishWorkflowTemplate(c *gin.Context) {
+	db := database.GetDB()
+	userRole, _ := c.Get("role")
+	if userRole.(string) != "admin" {
+		c.JSON(403, gin.H{"error": "只有系统管理员可以发布模板"})
+		return
+	}
+	template, ok := findTemplate(c, db)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Published *bool `json:"published" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Model(template).Update("is_published", *req.Published).Error; err != nil {
+		c.JSON(500, gin.H{"error": "发布模板失败"})
+		return
+	}
+	template.IsPublished = *req.Published
+	c.JSON(200, template.ToWorkflowTemplateResponse())
+}
+
+// 按模板创建工作流，当前用户为创建者
+func CreateWorkflowFromTemplate(c *gin.Context) {
+	db := database.GetDB()
+	template, ok := findTemplate(c, db)
+	if !ok {
+		return
+	}
+	instantiateWorkflow(c, db, template, "")
+}
+
+// 克隆工作流：复制名称模板、描述、类型、颜色、处理流程、自定义字段、成员角色与默认标签，不复制素材。
+// 原工作流的创建者在新工作流中为管理员；未指定名称时按名称模板生成，没有名称模板时在原名称后加“（副本）”
+func CloneWorkflow(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+
+	var workflow models.WorkflowGroup
+	if err := db.Preload("Members").First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	template := services.TemplateFromWorkflow(&workflow)
+	template.NamePattern = workflow.NamePattern
+	defaultName := ""
+	if workflow.NamePattern == "" {
+		defaultName = workflow.Name + "（副本）"
+	}
+	instantiateWorkflow(c, db, template, defaultName)
+}
@@ -3,23 +3,101 @@ package workflow
 import (
 	"ahsfnu-media-cloud/internal/database"
 	"ahsfnu-media-cloud/internal/models"
+	"ahsfnu-media-cloud/internal/services"
+	"encoding/json"
+	"log"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// 获取工作流列表
+// hasPermission 当前用户是否拥有工作流的某项权限
+func hasPermission(c *gin.Context, db *gorm.DB, workflow *models.WorkflowGroup, permission string) bool {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	return services.HasWorkflowPermission(db, workflow.ID, userID.(uint), userRole.(string), permission)
+}
+
+// workflowResponse 转换为安全的响应格式，并附带当前用户在工作流中的角色（需预加载 Members）
+func workflowResponse(c *gin.Context, workflow *models.WorkflowGroup) *models.WorkflowGroupResponse {
+	userID, _ := c.Get("user_id")
+	response := workflow.ToWorkflowGroupResponse()
+	response.MyRole = workflow.MemberRole(userID.(uint))
+	return response
+}
+
+// validMemberRole 检查成员角色，未指定时为摄影
+func validMemberRole(role string) (string, bool) {
+	if role == "" {
+		return models.WorkflowRolePhotographer, true
+	}
+	_, ok := models.WorkflowRoleLabels[role]
+	return role, ok
+}
+
+// validDeadline 截止时间必须晚于当前时间
+func validDeadline(c *gin.Context, deadline *time.Time) bool {
+	if deadline != nil && !deadline.After(time.Now()) {
+		c.JSON(400, gin.H{"error": "截止时间必须晚于当前时间"})
+		return false
+	}
+	return true
+}
+
+// validPipelineConfig 以当前用户的身份校验工作流类型与处理流程配置，返回规范化后的配置 JSON，未配置步骤时为空字符串
+func validPipelineConfig(c *gin.Context, db *gorm.DB, workflowType, config string) (string, bool) {
+	if _, ok := models.WorkflowTypeLabels[workflowType]; !ok {
+		c.JSON(400, gin.H{"error": "无效的工作流类型"})
+		return "", false
+	}
+	pipeline, err := services.ParsePipelineConfig(config)
+	if err == nil {
+		userID, _ := c.Get("user_id")
+		userRole, _ := c.Get("role")
+		err = services.ValidatePipelineConfig(db, workflowType, pipeline, userID.(uint), userRole.(string))
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return "", false
+	}
+	if len(pipeline.Steps) == 0 {
+		return "", true
+	}
+	normalized, _ := json.Marshal(pipeline)
+	return string(normalized), true
+}
+
+// 获取工作流列表，系统管理员可见全部，其他用户只能看到自己创建或参与的工作流
+// 可用 status 参数筛选进行中（active）或已归档（archived，含截止时间已过的）的工作流
 func GetWorkflows(c *gin.Context) {
 	db := database.GetDB()
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
 	var workflows []models.WorkflowGroup
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	keyword := c.Query("keyword")
 
 	query := db.Model(&models.WorkflowGroup{})
+	if userRole.(string) != "admin" {
+		query = query.Where("created_by = ? OR id IN (SELECT workflow_id FROM workflow_members WHERE user_id = ?)",
+			userID.(uint), userID.(uint))
+	}
 	if keyword != "" {
 		query = query.Where("name ILIKE ?", "%"+keyword+"%")
 	}
+	switch c.Query("status") {
+	case models.WorkflowStatusActive:
+		query = query.Where("status = ? AND (deadline IS NULL OR deadline > ?)", models.WorkflowStatusActive, time.Now())
+	case models.WorkflowStatusArchived:
+		query = query.Where("(status = ? OR deadline <= ?)", models.WorkflowStatusArchived, time.Now())
+	case "":
+	default:
+		c.JSON(400, gin.H{"error": "无效的工作流状态"})
+		return
+	}
 	var total int64
 	query.Model(&models.WorkflowGroup{}).Count(&total)
 	err := query.Preload("Creator").Preload("Members.User").Offset((page - 1) * pageSize).Limit(pageSize).Order("created_at DESC").Find(&workflows).Error
@@ -31,8 +109,7 @@ func GetWorkflows(c *gin.Context) {
 	// 转换为安全的响应格式
 	var workflowResponses []models.WorkflowGroupResponse
 	for i := range workflows {
-		workflowResponse := workflows[i].ToWorkflowGroupResponse()
-		workflowResponses = append(workflowResponses, *workflowResponse)
+		workflowResponses = append(workflowResponses, *workflowResponse(c, &workflows[i]))
 	}
 
 	c.JSON(200, gin.H{
@@ -57,30 +134,66 @@ func CreateWorkflow(c *gin.Context) {
 		Color       string `json:"color"`
 		IsActive    bool   `json:"is_active"`
 		Config      string `json:"config"`
-		Members     []uint `json:"members"`
+		Members     []uint `json:"members"` // 成员角色为摄影，可之后单独调整
+		// 公开文件是否去除 GPS、设备序列号等敏感元数据
+		StripMetadata bool `json:"strip_metadata"`
+		// 素材自定义字段定义
+		CustomFields []models.CustomFieldDefinition `json:"custom_fields"`
+		// 截止时间，到期后自动归档
+		Deadline *time.Time `json:"deadline"`
+		// 名称模板，克隆工作流时据此生成新名称
+		NamePattern string `json:"name_pattern"`
+		// 上传到工作流的素材自动添加的标签
+		DefaultTagIDs []uint `json:"default_tag_ids"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	if !validDeadline(c, req.Deadline) {
+		return
+	}
+	if err := services.ValidateCustomFieldSchema(req.CustomFields); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidateTagIDs(db, req.DefaultTagIDs); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置默认值
 	if req.Type == "" {
-		req.Type = "custom"
+		req.Type = models.WorkflowTypeCustom
 	}
 	if req.Color == "" {
 		req.Color = "#409EFF"
 	}
+	config, ok := validPipelineConfig(c, db, req.Type, req.Config)
+	if !ok {
+		return
+	}
 
 	workflow := models.WorkflowGroup{
-		Name:        req.Name,
-		Description: req.Description,
-		Type:        req.Type,
-		Color:       req.Color,
-		IsActive:    req.IsActive,
-		Config:      req.Config,
-		Status:      "active",
-		CreatedBy:   userID.(uint),
+		Name:          req.Name,
+		NamePattern:   req.NamePattern,
+		Description:   req.Description,
+		Type:          req.Type,
+		Color:         req.Color,
+		IsActive:      req.IsActive,
+		Config:        config,
+		Status:        models.WorkflowStatusActive,
+		StripMetadata: req.StripMetadata,
+		Deadline:      req.Deadline,
+		CreatedBy:     userID.(uint),
+	}
+	if len(req.CustomFields) > 0 {
+		schema, _ := json.Marshal(req.CustomFields)
+		workflow.CustomFieldSchema = string(schema)
+	}
+	if len(req.DefaultTagIDs) > 0 {
+		tags, _ := json.Marshal(req.DefaultTagIDs)
+		workflow.DefaultTags = string(tags)
 	}
 	if err := db.Create(&workflow).Error; err != nil {
 		c.JSON(500, gin.H{"error": "创建工作流失败"})
@@ -92,16 +205,17 @@ func CreateWorkflow(c *gin.Context) {
 		member := models.WorkflowMember{
 			WorkflowID: workflow.ID,
 			UserID:     uid,
-			Role:       "member",
+			Role:       models.WorkflowRolePhotographer,
+		}
+		if err := db.Create(&member).Error; err == nil {
+			notifyMemberAdded(db, &workflow, uid, userID.(uint))
 		}
-		db.Create(&member)
 	}
 
 	db.Preload("Creator").Preload("Members.User").First(&workflow, workflow.ID)
 
 	// 转换为安全的响应格式
-	workflowResponse := workflow.ToWorkflowGroupResponse()
-	c.JSON(201, workflowResponse)
+	c.JSON(201, workflowResponse(c, &workflow))
 }
 
 // 获取单个工作流详情
@@ -114,10 +228,13 @@ func GetWorkflow(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "工作流不存在"})
 		return
 	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermView) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
 
 	// 转换为安全的响应格式
-	workflowResponse := workflow.ToWorkflowGroupResponse()
-	c.JSON(200, workflowResponse)
+	c.JSON(200, workflowResponse(c, &workflow))
 }
 
 // 更新工作流
@@ -125,31 +242,71 @@ func UpdateWorkflow(c *gin.Context) {
 	db := database.GetDB()
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
 
 	var workflow models.WorkflowGroup
 	if err := db.First(&workflow, id).Error; err != nil {
 		c.JSON(404, gin.H{"error": "工作流不存在"})
 		return
 	}
-	if workflow.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
 		c.JSON(403, gin.H{"error": "没有权限"})
 		return
 	}
 
 	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Type        string `json:"type"`
-		Color       string `json:"color"`
-		IsActive    *bool  `json:"is_active"`
-		Config      string `json:"config"`
-		Members     []uint `json:"members"`
+		Name          string `json:"name"`
+		Description   string `json:"description"`
+		Type          string `json:"type"`
+		Color         string `json:"color"`
+		IsActive      *bool  `json:"is_active"`
+		Config        string `json:"config"`
+		Members       []uint `json:"members"` // 传入时整体替换成员，已有成员保留原角色，新成员为摄影
+		StripMetadata *bool  `json:"strip_metadata"`
+		// 传入时整体替换自定义字段定义，已删除字段的取值不再参与检索，并在素材下次更新时清除
+		CustomFields  *[]models.CustomFieldDefinition `json:"custom_fields"`
+		Deadline      *time.Time                      `json:"deadline"`
+		ClearDeadline bool                            `json:"clear_deadline"` // 取消截止时间
+		NamePattern   *string                         `json:"name_pattern"`
+		DefaultTagIDs *[]uint                         `json:"default_tag_ids"` // 传入时整体替换默认标签
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	if workflow.IsArchived() {
+		c.JSON(400, gin.H{"error": "工作流已归档，请先重新开启"})
+		return
+	}
+	if !validDeadline(c, req.Deadline) {
+		return
+	}
+	if req.CustomFields != nil {
+		if err := services.ValidateCustomFieldSchema(*req.CustomFields); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.DefaultTagIDs != nil {
+		if err := services.ValidateTagIDs(db, *req.DefaultTagIDs); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	// 类型变化时已有的处理流程也需符合新类型
+	var config string
+	if req.Type != "" || req.Config != "" {
+		workflowType, rawConfig := workflow.Type, workflow.Config
+		if req.Type != "" {
+			workflowType = req.Type
+		}
+		if req.Config != "" {
+			rawConfig = req.Config
+		}
+		var ok bool
+		if config, ok = validPipelineConfig(c, db, workflowType, rawConfig); !ok {
+			return
+		}
+	}
 
 	updates := map[string]interface{}{}
 	if req.Name != "" {
@@ -168,30 +325,81 @@ func UpdateWorkflow(c *gin.Context) {
 		updates["is_active"] = *req.IsActive
 	}
 	if req.Config != "" {
-		updates["config"] = req.Config
+		updates["config"] = config
+	}
+	policyChanged := req.StripMetadata != nil && *req.StripMetadata != workflow.StripMetadata
+	if req.StripMetadata != nil {
+		updates["strip_metadata"] = *req.StripMetadata
+	}
+	if req.Deadline != nil {
+		updates["deadline"] = *req.Deadline
+	} else if req.ClearDeadline {
+		updates["deadline"] = nil
+	}
+	if req.NamePattern != nil {
+		updates["name_pattern"] = *req.NamePattern
+	}
+	if req.DefaultTagIDs != nil {
+		updates["default_tags"] = ""
+		if len(*req.DefaultTagIDs) > 0 {
+			tags, _ := json.Marshal(*req.DefaultTagIDs)
+			updates["default_tags"] = string(tags)
+		}
+	}
+	if req.CustomFields != nil {
+		updates["custom_field_schema"] = ""
+		if len(*req.CustomFields) > 0 {
+			schema, _ := json.Marshal(*req.CustomFields)
+			updates["custom_field_schema"] = string(schema)
+		}
 	}
 	if err := db.Model(&workflow).Updates(updates).Error; err != nil {
 		c.JSON(500, gin.H{"error": "更新失败"})
 		return
 	}
 
-	// 更新成员（先删后加）
+	// 名称、描述或自定义字段变化后同步相关素材的检索索引
+	if req.Name != "" || req.Description != "" || req.CustomFields != nil {
+		services.ReindexMaterialsAsync(db, "workflow_id = ?", workflow.ID)
+	}
+
+	// 元数据策略变化后，在后台重新生成或清理该工作流下素材的公开副本
+	if policyChanged {
+		go func(workflowID uint) {
+			if err := services.NewUploadService().ApplyWorkflowMetadataPolicy(db, workflowID); err != nil {
+				log.Printf("同步工作流 %d 的公开副本失败: %v", workflowID, err)
+			}
+		}(workflow.ID)
+	}
+
+	// 更新成员（先删后加），已有成员保留原角色
 	if req.Members != nil {
+		var existing []models.WorkflowMember
+		db.Where("workflow_id = ?", workflow.ID).Find(&existing)
+		roles := make(map[uint]string)
+		for _, member := range existing {
+			roles[member.UserID] = member.Role
+		}
 		db.Where("workflow_id = ?", workflow.ID).Delete(&models.WorkflowMember{})
 		for _, uid := range req.Members {
+			role, ok := roles[uid]
+			if !ok {
+				role = models.WorkflowRolePhotographer
+			}
 			member := models.WorkflowMember{
 				WorkflowID: workflow.ID,
 				UserID:     uid,
-				Role:       "member",
+				Role:       role,
+			}
+			if err := db.Create(&member).Error; err == nil && !ok {
+				notifyMemberAdded(db, &workflow, uid, userID.(uint))
 			}
-			db.Create(&member)
 		}
 	}
 	db.Preload("Creator").Preload("Members.User").First(&workflow, workflow.ID)
 
 	// 转换为安全的响应格式
-	workflowResponse := workflow.ToWorkflowGroupResponse()
-	c.JSON(200, workflowResponse)
+	c.JSON(200, workflowResponse(c, &workflow))
 }
 
 // 删除工作流
@@ -211,30 +419,68 @@ func DeleteWorkflow(c *gin.Context) {
 		return
 	}
 
-	// 先将该工作流下所有素材的 workflow_id 置为 NULL
-	if err := db.Model(&models.Material{}).Where("workflow_id = ?", workflow.ID).Update("workflow_id", nil).Error; err != nil {
+	// 记录受影响的素材，解除关联后同步检索索引
+	var materialIDs []uint
+	db.Model(&models.Material{}).Where("workflow_id = ?", workflow.ID).Pluck("id", &materialIDs)
+
+	// 记录审核状态的重置
+	var reviewed []models.Material
+	db.Select("id, review_status").Where("workflow_id = ? AND review_status <> ''", workflow.ID).Find(&reviewed)
+	for _, material := range reviewed {
+		db.Create(&models.MaterialReviewLog{
+			MaterialID: material.ID,
+			WorkflowID: &workflow.ID,
+			FromStatus: material.ReviewStatus,
+			Reason:     "工作流已删除",
+			ActorID:    userID.(uint),
+		})
+	}
+
+	// 先将该工作流下所有素材的 workflow_id 置为 NULL，审核状态随之清空
+	err := db.Model(&models.Material{}).Where("workflow_id = ?", workflow.ID).
+		Updates(map[string]interface{}{"workflow_id": nil, "review_status": ""}).Error
+	if err != nil {
 		c.JSON(500, gin.H{"error": "解除素材与工作流关系失败"})
 		return
 	}
 
 	db.Where("workflow_id = ?", workflow.ID).Delete(&models.WorkflowMember{})
+	if err := services.DeletePipelineRuns(db, "workflow_id = ?", workflow.ID); err != nil {
+		log.Printf("删除工作流 %d 的处理记录失败: %v", workflow.ID, err)
+	}
 	db.Delete(&workflow)
+	if len(materialIDs) > 0 {
+		services.ReindexMaterialsAsync(db, "id IN ?", materialIDs)
+	}
 	c.JSON(200, gin.H{"message": "删除成功，素材已解除关联"})
 }
 
+// notifyMemberAdded 通知用户已被添加为工作流成员
+func notifyMemberAdded(db *gorm.DB, workflow *models.WorkflowGroup, memberID, actorID uint) {
+	err := services.Notify(db, &models.Notification{
+		UserID:     memberID,
+		Type:       models.NotificationWorkflowMember,
+		Title:      "你被添加到工作流「" + workflow.Name + "」",
+		ActorID:    &actorID,
+		WorkflowID: &workflow.ID,
+	})
+	if err != nil {
+		log.Printf("发送工作流成员通知失败: %v", err)
+	}
+}
+
 // 添加成员
 func AddWorkflowMember(c *gin.Context) {
 	db := database.GetDB()
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
 
 	var workflow models.WorkflowGroup
 	if err := db.First(&workflow, id).Error; err != nil {
 		c.JSON(404, gin.H{"error": "工作流不存在"})
 		return
 	}
-	if workflow.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
 		c.JSON(403, gin.H{"error": "没有权限"})
 		return
 	}
@@ -247,8 +493,17 @@ func AddWorkflowMember(c *gin.Context) {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	if req.Role == "" {
-		req.Role = "member"
+	role, ok := validMemberRole(req.Role)
+	if !ok {
+		c.JSON(400, gin.H{"error": "无效的成员角色"})
+		return
+	}
+	req.Role = role
+	var count int64
+	db.Model(&models.WorkflowMember{}).Where("workflow_id = ? AND user_id = ?", workflow.ID, req.UserID).Count(&count)
+	if count > 0 {
+		c.JSON(400, gin.H{"error": "该用户已是工作流成员"})
+		return
 	}
 	member := models.WorkflowMember{
 		WorkflowID: workflow.ID,
@@ -259,6 +514,7 @@ func AddWorkflowMember(c *gin.Context) {
 		c.JSON(500, gin.H{"error": "添加成员失败"})
 		return
 	}
+	notifyMemberAdded(db, &workflow, req.UserID, userID.(uint))
 
 	// 预加载用户信息并转换为安全的响应格式
 	db.Preload("User").First(&member, member.ID)
@@ -266,20 +522,60 @@ func AddWorkflowMember(c *gin.Context) {
 	c.JSON(200, memberResponse)
 }
 
+// 修改成员角色
+func UpdateWorkflowMemberRole(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+	memberID := c.Param("userId")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := validMemberRole(req.Role); !ok {
+		c.JSON(400, gin.H{"error": "无效的成员角色"})
+		return
+	}
+
+	var member models.WorkflowMember
+	if err := db.Where("workflow_id = ? AND user_id = ?", workflow.ID, memberID).First(&member).Error; err != nil {
+		c.JSON(404, gin.H{"error": "成员不存在"})
+		return
+	}
+	if err := db.Model(&member).Update("role", req.Role).Error; err != nil {
+		c.JSON(500, gin.H{"error": "修改成员角色失败"})
+		return
+	}
+
+	db.Preload("User").First(&member, member.ID)
+	c.JSON(200, member.ToWorkflowMemberResponse())
+}
+
 // 移除成员
 func RemoveWorkflowMember(c *gin.Context) {
 	db := database.GetDB()
 	id := c.Param("id")
 	memberID := c.Param("userId")
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
 
 	var workflow models.WorkflowGroup
 	if err := db.First(&workflow, id).Error; err != nil {
 		c.JSON(404, gin.H{"error": "工作流不存在"})
 		return
 	}
-	if workflow.CreatedBy != userID.(uint) && userRole.(string) != "admin" {
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
 		c.JSON(403, gin.H{"error": "没有权限"})
 		return
 	}
@@ -290,3 +586,221 @@ func RemoveWorkflowMember(c *gin.Context) {
 	}
 	c.JSON(200, gin.H{"message": "成员已移除"})
 }
+
+// 归档工作流，归档后工作流及其素材只读
+func ArchiveWorkflow(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	if workflow.Status == models.WorkflowStatusArchived {
+		c.JSON(400, gin.H{"error": "工作流已归档"})
+		return
+	}
+	if err := services.ArchiveWorkflow(db, workflow.ID); err != nil {
+		c.JSON(500, gin.H{"error": "归档失败"})
+		return
+	}
+
+	db.Preload("Creator").Preload("Members.User").First(&workflow, workflow.ID)
+	c.JSON(200, workflowResponse(c, &workflow))
+}
+
+// 重新开启已归档的工作流；原截止时间已过时需要设置新的截止时间，否则取消截止时间
+func ReopenWorkflow(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	if !workflow.IsArchived() {
+		c.JSON(400, gin.H{"error": "工作流未归档"})
+		return
+	}
+
+	var req struct {
+		Deadline *time.Time `json:"deadline"`
+	}
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !validDeadline(c, req.Deadline) {
+		return
+	}
+	deadline := req.Deadline
+	if deadline == nil && workflow.Deadline != nil && workflow.Deadline.After(time.Now()) {
+		deadline = workflow.Deadline
+	}
+	if err := services.ReopenWorkflow(db, workflow.ID, deadline); err != nil {
+		c.JSON(500, gin.H{"error": "重新开启失败"})
+		return
+	}
+
+	db.Preload("Creator").Preload("Members.User").First(&workflow, workflow.ID)
+	c.JSON(200, workflowResponse(c, &workflow))
+}
+
+// 将已归档工作流的素材移入冷存储（后台执行，缩略图保留在热存储）
+func MoveWorkflowToColdStorage(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	if !workflow.IsArchived() {
+		c.JSON(400, gin.H{"error": "只有已归档的工作流才能移入冷存储"})
+		return
+	}
+	if _, err := services.NewColdStorage(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func(workflowID uint) {
+		if err := services.NewUploadService().MoveWorkflowToCold(db, workflowID); err != nil {
+			log.Printf("工作流 %d 移入冷存储失败: %v", workflowID, err)
+		}
+	}(workflow.ID)
+
+	c.JSON(202, gin.H{"message": "已开始移入冷存储"})
+}
+
+// 对工作流中已有的素材手动执行处理流程（后台执行），未指定素材时处理全部适用的素材
+func RunWorkflowPipeline(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermManage) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	if workflow.IsArchived() {
+		c.JSON(400, gin.H{"error": "工作流已归档，请先重新开启"})
+		return
+	}
+	if len(workflow.Pipeline().Steps) == 0 {
+		c.JSON(400, gin.H{"error": "工作流未配置处理流程"})
+		return
+	}
+
+	var req struct {
+		MaterialIDs []uint `json:"material_ids"`
+	}
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	query := db.Model(&models.Material{}).Where("workflow_id = ?", workflow.ID)
+	switch workflow.Type {
+	case models.WorkflowTypeImageProcessing:
+		query = query.Where("file_type = ?", "image")
+	case models.WorkflowTypeVideoProcessing:
+		query = query.Where("file_type = ?", "video")
+	}
+	if len(req.MaterialIDs) > 0 {
+		query = query.Where("id IN ?", req.MaterialIDs)
+	}
+	var materialIDs []uint
+	if err := query.Order("id ASC").Pluck("id", &materialIDs).Error; err != nil {
+		c.JSON(500, gin.H{"error": "获取素材失败"})
+		return
+	}
+	if len(materialIDs) == 0 {
+		c.JSON(400, gin.H{"error": "没有可处理的素材"})
+		return
+	}
+
+	triggeredBy := userID.(uint)
+	services.NewUploadService().RunPipelineAsync(db, materialIDs, models.PipelineTriggerManual, &triggeredBy)
+	c.JSON(202, gin.H{
+		"message":      "已开始处理",
+		"material_ids": materialIDs,
+	})
+}
+
+// 获取工作流处理流程的执行记录，可按素材与状态筛选，最新的排在前面
+func GetWorkflowPipelineRuns(c *gin.Context) {
+	db := database.GetDB()
+	id := c.Param("id")
+
+	var workflow models.WorkflowGroup
+	if err := db.First(&workflow, id).Error; err != nil {
+		c.JSON(404, gin.H{"error": "工作流不存在"})
+		return
+	}
+	if !hasPermission(c, db, &workflow, models.WorkflowPermView) {
+		c.JSON(403, gin.H{"error": "没有权限"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := db.Model(&models.PipelineRun{}).Where("workflow_id = ?", workflow.ID)
+	if materialID := c.Query("material_id"); materialID != "" {
+		query = query.Where("material_id = ?", materialID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	query.Count(&total)
+	var runs []models.PipelineRun
+	err := query.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order("started_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取处理记录失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"data": runs,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
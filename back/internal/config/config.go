@@ -3,21 +3,32 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 运行环境可能没有时区数据库
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Upload   UploadConfig
-	HMAC     HMACConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Upload     UploadConfig
+	HMAC       HMACConfig
+	Quality    QualityConfig
+	Storage    StorageConfig
+	Encryption EncryptionConfig
+	Embed      EmbedConfig
 }
 
+// ServerConfig 服务配置，Timezone 用于按日期分组、解析日期与 EXIF 拍摄时间
 type ServerConfig struct {
-	Port string
-	Mode string
+	Port     string
+	Mode     string
+	Timezone string // IANA 时区名，如 Asia/Shanghai
+	Location *time.Location
 }
 
 type DatabaseConfig struct {
@@ -44,6 +55,38 @@ type HMACConfig struct {
 	SecretKey string
 }
 
+// QualityConfig 图片质量评估阈值，低于阈值的图片会被标记为可能的废片
+type QualityConfig struct {
+	BlurThreshold     float64 // 清晰度（拉普拉斯方差）阈值
+	ExposureThreshold float64 // 曝光得分阈值（0-100）
+}
+
+// StorageConfig 冷存储配置，ColdBackend 为空表示不启用冷存储
+type StorageConfig struct {
+	ColdBackend string // local, s3
+	ColdPath    string // local 模式下的冷存储目录
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// EncryptionConfig 原始文件加密配置，MasterKey 为空表示不启用加密
+type EncryptionConfig struct {
+	MasterKey    string // base64 编码的 32 字节主密钥
+	MasterKeyID  string // 当前主密钥标识，轮换时更换
+	PreviousKeys string // 历史主密钥，格式 id:base64,id:base64
+}
+
+// EmbedConfig 公开素材嵌入配置
+type EmbedConfig struct {
+	PublicBaseURL  string   // 对外访问地址，嵌入页与 oEmbed 接口必须配置
+	LinkURL        string   // 嵌入内容回链地址模板，{id} 替换为素材ID，为空时指向素材详情页
+	ProviderName   string   // oEmbed 提供方名称
+	AllowedOrigins []string // 允许以 iframe 嵌入的来源，支持 https://*.example.com 形式，为空表示不限制
+}
+
 var AppConfig *Config
 
 func Init() {
@@ -54,8 +97,9 @@ func Init() {
 
 	AppConfig = &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Port:     getEnv("SERVER_PORT", "8080"),
+			Mode:     getEnv("GIN_MODE", "debug"),
+			Timezone: getEnv("TIMEZONE", "Asia/Shanghai"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -77,7 +121,51 @@ func Init() {
 		HMAC: HMACConfig{
 			SecretKey: getEnv("HMAC_SECRET", "your-hmac-secret-key"),
 		},
+		Quality: QualityConfig{
+			BlurThreshold:     getEnvFloat("QUALITY_BLUR_THRESHOLD", 100),
+			ExposureThreshold: getEnvFloat("QUALITY_EXPOSURE_THRESHOLD", 80),
+		},
+		Storage: StorageConfig{
+			ColdBackend: getEnv("COLD_STORAGE_BACKEND", ""),
+			ColdPath:    getEnv("COLD_STORAGE_PATH", "./cold"),
+			S3Endpoint:  getEnv("COLD_S3_ENDPOINT", ""),
+			S3Region:    getEnv("COLD_S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("COLD_S3_BUCKET", ""),
+			S3AccessKey: getEnv("COLD_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("COLD_S3_SECRET_KEY", ""),
+		},
+		Encryption: EncryptionConfig{
+			MasterKey:    getEnv("ENCRYPTION_MASTER_KEY", ""),
+			MasterKeyID:  getEnv("ENCRYPTION_MASTER_KEY_ID", "default"),
+			PreviousKeys: getEnv("ENCRYPTION_PREVIOUS_KEYS", ""),
+		},
+		Embed: EmbedConfig{
+			PublicBaseURL:  strings.TrimRight(getEnv("PUBLIC_BASE_URL", ""), "/"),
+			LinkURL:        getEnv("EMBED_LINK_URL", ""),
+			ProviderName:   getEnv("EMBED_PROVIDER_NAME", "AHSFNU Media Cloud"),
+			AllowedOrigins: getEnvList("EMBED_ALLOWED_ORIGINS"),
+		},
 	}
+	AppConfig.Server.Timezone, AppConfig.Server.Location = loadLocation(AppConfig.Server.Timezone)
+}
+
+// Location 返回配置的时区，未初始化配置时为 UTC
+func Location() *time.Location {
+	if AppConfig == nil || AppConfig.Server.Location == nil {
+		return time.UTC
+	}
+	return AppConfig.Server.Location
+}
+
+// loadLocation 加载时区，时区名同时会写入 SQL（AT TIME ZONE），无效时使用 UTC
+func loadLocation(name string) (string, *time.Location) {
+	if name != "Local" && !strings.ContainsAny(name, "'\\") {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return name, loc
+		}
+	}
+	log.Printf("Invalid value for TIMEZONE: %q, using UTC", name)
+	return "UTC", time.UTC
 }
 
 func getEnv(key, defaultValue string) string {
@@ -86,3 +174,24 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList 读取逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+		log.Printf("Invalid value for %s, using default %v", key, defaultValue)
+	}
+	return defaultValue
+}
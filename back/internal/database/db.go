@@ -36,10 +36,28 @@ func Init() {
 		&models.User{},
 		&models.InviteCode{},
 		&models.Material{},
+		&models.MaterialColor{},
+		&models.MaterialSearch{},
 		&models.Tag{},
 		&models.MaterialTag{},
 		&models.WorkflowGroup{},
 		&models.WorkflowMember{},
+		&models.WorkflowTemplate{},
+		&models.SmartAlbum{},
+		&models.SmartAlbumShare{},
+		&models.SmartAlbumView{},
+		&models.Album{},
+		&models.AlbumCollaborator{},
+		&models.AlbumItem{},
+		&models.ShareLink{},
+		&models.MaterialComment{},
+		&models.MaterialReviewLog{},
+		&models.PipelineRun{},
+		&models.PipelineStepResult{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.Place{},
+		&models.MaterialPlace{},
 	)
 
 	if err != nil {
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SmartAlbum 智能相册：保存的一组素材搜索参数，查看时实时计算结果
+type SmartAlbum struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null;size:100"`
+	Description string `json:"description,omitempty"`
+	Params      string `json:"-" gorm:"type:text"` // 搜索参数，JSON 对象
+	// 是否显示自上次查看以来新增的匹配数量
+	ShowBadge bool `json:"show_badge" gorm:"default:false"`
+	// 共享给该工作流的所有成员
+	SharedWorkflowID *uint     `json:"shared_workflow_id,omitempty"`
+	CreatedBy        uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 关联关系
+	Creator *User             `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Shares  []SmartAlbumShare `json:"shares,omitempty" gorm:"foreignKey:AlbumID"`
+}
+
+// SmartAlbumShare 智能相册共享给的用户
+type SmartAlbumShare struct {
+	ID      uint `json:"id" gorm:"primaryKey"`
+	AlbumID uint `json:"album_id" gorm:"not null;uniqueIndex:idx_smart_album_share"`
+	UserID  uint `json:"user_id" gorm:"not null;uniqueIndex:idx_smart_album_share"`
+}
+
+// SmartAlbumView 用户最后一次查看智能相册的时间，用于计算新增数量
+type SmartAlbumView struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	AlbumID      uint      `json:"album_id" gorm:"not null;uniqueIndex:idx_smart_album_view"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_smart_album_view"`
+	LastViewedAt time.Time `json:"last_viewed_at"`
+}
+
+// SmartAlbumResponse 用于返回给前端的智能相册信息
+type SmartAlbumResponse struct {
+	ID               uint              `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	Params           map[string]string `json:"params"`
+	ShowBadge        bool              `json:"show_badge"`
+	SharedWorkflowID *uint             `json:"shared_workflow_id,omitempty"`
+	SharedUserIDs    []uint            `json:"shared_user_ids"`
+	CreatedBy        uint              `json:"created_by"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Creator          *SafeUser         `json:"creator,omitempty"`
+	NewCount         *int64            `json:"new_count,omitempty"` // 自上次查看以来新增的匹配数量
+}
+
+// ParamMap 解析保存的搜索参数
+func (a *SmartAlbum) ParamMap() map[string]string {
+	params := make(map[string]string)
+	if a.Params != "" {
+		_ = json.Unmarshal([]byte(a.Params), &params)
+	}
+	return params
+}
+
+// ToSmartAlbumResponse 将 SmartAlbum 转换为 SmartAlbumResponse
+func (a *SmartAlbum) ToSmartAlbumResponse() *SmartAlbumResponse {
+	response := &SmartAlbumResponse{
+		ID:               a.ID,
+		Name:             a.Name,
+		Description:      a.Description,
+		Params:           a.ParamMap(),
+		ShowBadge:        a.ShowBadge,
+		SharedWorkflowID: a.SharedWorkflowID,
+		SharedUserIDs:    []uint{},
+		CreatedBy:        a.CreatedBy,
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
+	}
+	for _, share := range a.Shares {
+		response.SharedUserIDs = append(response.SharedUserIDs, share.UserID)
+	}
+	if a.Creator != nil {
+		response.Creator = a.Creator.ToSafeUser()
+	}
+	return response
+}
+
+// 相册协作者角色
+const (
+	AlbumRoleEditor = "editor" // 可以增删素材、调整顺序、设置封面和修改相册信息
+	AlbumRoleViewer = "viewer" // 只能查看
+)
+
+// Album 手动整理的相册：素材可以同时属于多个相册，与其所属工作流无关
+type Album struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null;size:100"`
+	Description string `json:"description,omitempty"`
+	// 封面素材，未设置时使用第一个素材
+	CoverMaterialID *uint     `json:"cover_material_id,omitempty"`
+	CreatedBy       uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 关联关系
+	Creator       *User               `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Collaborators []AlbumCollaborator `json:"collaborators,omitempty" gorm:"foreignKey:AlbumID"`
+}
+
+// AlbumCollaborator 相册协作者
+type AlbumCollaborator struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	AlbumID uint   `json:"album_id" gorm:"not null;uniqueIndex:idx_album_collaborator"`
+	UserID  uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_album_collaborator"`
+	Role    string `json:"role" gorm:"size:20;default:'viewer'"`
+
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// AlbumItem 相册中的素材，按 Position 手动排序
+type AlbumItem struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AlbumID    uint      `json:"album_id" gorm:"not null;uniqueIndex:idx_album_item"`
+	MaterialID uint      `json:"material_id" gorm:"not null;uniqueIndex:idx_album_item;index"`
+	Position   int       `json:"position" gorm:"not null;default:0"`
+	AddedBy    uint      `json:"added_by"`
+	AddedAt    time.Time `json:"added_at" gorm:"autoCreateTime"`
+}
+
+// AlbumCollaboratorResponse 相册协作者信息
+type AlbumCollaboratorResponse struct {
+	UserID uint      `json:"user_id"`
+	Role   string    `json:"role"`
+	User   *SafeUser `json:"user,omitempty"`
+}
+
+// AlbumResponse 用于返回给前端的相册信息
+type AlbumResponse struct {
+	ID              uint                        `json:"id"`
+	Name            string                      `json:"name"`
+	Description     string                      `json:"description,omitempty"`
+	CoverMaterialID *uint                       `json:"cover_material_id,omitempty"`
+	CoverThumbnail  string                      `json:"cover_thumbnail,omitempty"`
+	ItemCount       int64                       `json:"item_count"`
+	Collaborators   []AlbumCollaboratorResponse `json:"collaborators"`
+	CanEdit         bool                        `json:"can_edit"` // 当前用户能否修改相册内容
+	CreatedBy       uint                        `json:"created_by"`
+	CreatedAt       time.Time                   `json:"created_at"`
+	UpdatedAt       time.Time                   `json:"updated_at"`
+	Creator         *SafeUser                   `json:"creator,omitempty"`
+}
+
+// ToAlbumResponse 将 Album 转换为 AlbumResponse
+func (a *Album) ToAlbumResponse() *AlbumResponse {
+	response := &AlbumResponse{
+		ID:              a.ID,
+		Name:            a.Name,
+		Description:     a.Description,
+		CoverMaterialID: a.CoverMaterialID,
+		Collaborators:   []AlbumCollaboratorResponse{},
+		CreatedBy:       a.CreatedBy,
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
+	}
+	for _, collaborator := range a.Collaborators {
+		item := AlbumCollaboratorResponse{UserID: collaborator.UserID, Role: collaborator.Role}
+		if collaborator.User != nil {
+			item.User = collaborator.User.ToSafeUser()
+		}
+		response.Collaborators = append(response.Collaborators, item)
+	}
+	if a.Creator != nil {
+		response.Creator = a.Creator.ToSafeUser()
+	}
+	return response
+}
+
+// CollaboratorRole 返回用户在相册中的协作角色，不是协作者时返回空串
+func (a *Album) CollaboratorRole(userID uint) string {
+	for _, collaborator := range a.Collaborators {
+		if collaborator.UserID == userID {
+			return collaborator.Role
+		}
+	}
+	return ""
+}
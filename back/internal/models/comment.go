@@ -0,0 +1,119 @@
+package models
+
+import "time"
+
+// MaterialComment 素材评论。顶层评论构成讨论串，回复挂在顶层评论下；
+// 顶层评论可以锚定到图片上的矩形区域或视频的时间段
+type MaterialComment struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	MaterialID uint   `json:"material_id" gorm:"not null;index"`
+	ParentID   *uint  `json:"parent_id,omitempty" gorm:"index"`
+	UserID     uint   `json:"user_id" gorm:"not null"`
+	Content    string `json:"content" gorm:"type:text;not null"`
+	// 图片区域，坐标与宽高均为相对图片尺寸的比例（0-1）
+	RegionX      *float64 `json:"-"`
+	RegionY      *float64 `json:"-"`
+	RegionWidth  *float64 `json:"-"`
+	RegionHeight *float64 `json:"-"`
+	// 视频时间段（秒）
+	TimeStart  *float64   `json:"-"`
+	TimeEnd    *float64   `json:"-"`
+	Resolved   bool       `json:"resolved" gorm:"default:false"`
+	ResolvedBy *uint      `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// 关联关系
+	User    *User             `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Replies []MaterialComment `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// CommentRegion 评论锚定的图片区域
+type CommentRegion struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// CommentTimeRange 评论锚定的视频时间段
+type CommentTimeRange struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// MaterialCommentResponse 用于返回给前端的评论信息
+type MaterialCommentResponse struct {
+	ID         uint                      `json:"id"`
+	MaterialID uint                      `json:"material_id"`
+	ParentID   *uint                     `json:"parent_id,omitempty"`
+	Content    string                    `json:"content"`
+	Region     *CommentRegion            `json:"region,omitempty"`
+	TimeRange  *CommentTimeRange         `json:"time_range,omitempty"`
+	Resolved   bool                      `json:"resolved"`
+	ResolvedBy *uint                     `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time                `json:"resolved_at,omitempty"`
+	EditedAt   *time.Time                `json:"edited_at,omitempty"`
+	CreatedAt  time.Time                 `json:"created_at"`
+	User       *SafeUser                 `json:"user,omitempty"`
+	Replies    []MaterialCommentResponse `json:"replies,omitempty"`
+}
+
+// Region 评论锚定的图片区域，未锚定时返回 nil
+func (m *MaterialComment) Region() *CommentRegion {
+	if m.RegionX == nil || m.RegionY == nil || m.RegionWidth == nil || m.RegionHeight == nil {
+		return nil
+	}
+	return &CommentRegion{X: *m.RegionX, Y: *m.RegionY, Width: *m.RegionWidth, Height: *m.RegionHeight}
+}
+
+// SetRegion 设置或清除锚定的图片区域
+func (m *MaterialComment) SetRegion(region *CommentRegion) {
+	if region == nil {
+		m.RegionX, m.RegionY, m.RegionWidth, m.RegionHeight = nil, nil, nil, nil
+		return
+	}
+	m.RegionX, m.RegionY, m.RegionWidth, m.RegionHeight = &region.X, &region.Y, &region.Width, &region.Height
+}
+
+// TimeRange 评论锚定的视频时间段，未锚定时返回 nil
+func (m *MaterialComment) TimeRange() *CommentTimeRange {
+	if m.TimeStart == nil || m.TimeEnd == nil {
+		return nil
+	}
+	return &CommentTimeRange{Start: *m.TimeStart, End: *m.TimeEnd}
+}
+
+// SetTimeRange 设置或清除锚定的视频时间段
+func (m *MaterialComment) SetTimeRange(timeRange *CommentTimeRange) {
+	if timeRange == nil {
+		m.TimeStart, m.TimeEnd = nil, nil
+		return
+	}
+	m.TimeStart, m.TimeEnd = &timeRange.Start, &timeRange.End
+}
+
+// ToMaterialCommentResponse 将 MaterialComment 转换为 MaterialCommentResponse
+func (m *MaterialComment) ToMaterialCommentResponse() *MaterialCommentResponse {
+	response := &MaterialCommentResponse{
+		ID:         m.ID,
+		MaterialID: m.MaterialID,
+		ParentID:   m.ParentID,
+		Content:    m.Content,
+		Region:     m.Region(),
+		TimeRange:  m.TimeRange(),
+		Resolved:   m.Resolved,
+		ResolvedBy: m.ResolvedBy,
+		ResolvedAt: m.ResolvedAt,
+		EditedAt:   m.EditedAt,
+		CreatedAt:  m.CreatedAt,
+	}
+	if m.User != nil {
+		response.User = m.User.ToSafeUser()
+	}
+	for i := range m.Replies {
+		response.Replies = append(response.Replies, *m.Replies[i].ToMaterialCommentResponse())
+	}
+	return response
+}
@@ -1,56 +1,125 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
 type Material struct {
-	ID               uint      `json:"id" gorm:"primaryKey"`
-	Filename         string    `json:"filename" gorm:"not null;size:255"`
-	OriginalFilename string    `json:"original_filename" gorm:"not null;size:255"`
-	FilePath         string    `json:"file_path" gorm:"not null;size:500"`
-	FileSize         int64     `json:"file_size" gorm:"not null"`
-	FileType         string    `json:"file_type" gorm:"not null;size:50"` // image, video
-	MimeType         string    `json:"mime_type" gorm:"not null;size:100"`
-	Width            *int      `json:"width,omitempty"`
-	Height           *int      `json:"height,omitempty"`
-	Duration         *int      `json:"duration,omitempty"` // 视频时长(秒)
-	UploadedBy       uint      `json:"uploaded_by" gorm:"not null"`
-	WorkflowID       *uint     `json:"workflow_id,omitempty"`
-	UploadTime       time.Time `json:"upload_time" gorm:"autoCreateTime"`
-	IsStarred        bool      `json:"is_starred" gorm:"default:false"`
-	IsPublic         bool      `json:"is_public" gorm:"default:false"` // 是否公开
-	ThumbnailPath    string    `json:"thumbnail_path,omitempty" gorm:"size:500"`
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	Filename         string     `json:"filename" gorm:"not null;size:255"`
+	OriginalFilename string     `json:"original_filename" gorm:"not null;size:255"`
+	FilePath         string     `json:"file_path" gorm:"not null;size:500;index"`
+	FileSize         int64      `json:"file_size" gorm:"not null"`
+	FileType         string     `json:"file_type" gorm:"not null;size:50"` // image, video
+	MimeType         string     `json:"mime_type" gorm:"not null;size:100"`
+	Width            *int       `json:"width,omitempty"`
+	Height           *int       `json:"height,omitempty"`
+	Duration         *int       `json:"duration,omitempty"` // 视频时长(秒)
+	UploadedBy       uint       `json:"uploaded_by" gorm:"not null"`
+	WorkflowID       *uint      `json:"workflow_id,omitempty"`
+	UploadTime       time.Time  `json:"upload_time" gorm:"autoCreateTime"`
+	IsStarred        bool       `json:"is_starred" gorm:"default:false"`
+	IsPublic         bool       `json:"is_public" gorm:"default:false"` // 是否公开
+	ThumbnailPath    string     `json:"thumbnail_path,omitempty" gorm:"size:500"`
+	PublicPath       string     `json:"-" gorm:"size:500"`                         // 去除敏感元数据后的公开副本
+	ProcessedPath    string     `json:"processed_path,omitempty" gorm:"size:500"`  // 工作流处理流程生成的成品文件
+	StripMetadata    *bool      `json:"strip_metadata,omitempty"`                  // 为空时继承所属工作流的策略
+	Palette          string     `json:"palette,omitempty" gorm:"size:100"`         // 主色，逗号分隔的十六进制颜色
+	SharpnessScore   *float64   `json:"sharpness_score,omitempty"`                 // 清晰度（拉普拉斯方差），越低越模糊
+	ExposureScore    *float64   `json:"exposure_score,omitempty"`                  // 曝光得分（0-100），越低截断越严重
+	StorageTier      string     `json:"storage_tier" gorm:"default:'hot';size:10"` // hot, cold
+	RestoreStatus    string     `json:"restore_status,omitempty" gorm:"size:20"`   // restoring, restored, failed
+	TieredAt         *time.Time `json:"tiered_at,omitempty"`                       // 移入冷存储的时间
+	Encrypted        bool       `json:"encrypted" gorm:"default:false"`            // 原始文件是否加密存储
+	TakenAt          *time.Time `json:"taken_at,omitempty" gorm:"index"`           // 拍摄时间（EXIF 或视频元数据）
+	Latitude         *float64   `json:"-" gorm:"index:idx_material_location"`      // 拍摄位置纬度，通过 GeoJSON 接口按隐私规则提供
+	Longitude        *float64   `json:"-" gorm:"index:idx_material_location"`      // 拍摄位置经度
+	WrappedKey       string     `json:"-" gorm:"size:200"`                         // 被主密钥包装的数据密钥
+	KeyID            string     `json:"-" gorm:"size:50"`                          // 包装数据密钥所用的主密钥标识
+
+	Caption      string `json:"caption,omitempty" gorm:"size:500"`                       // 图注
+	Description  string `json:"description,omitempty" gorm:"type:text"`                  // 描述
+	CustomFields string `json:"-" gorm:"type:text"`                                      // 所属工作流定义的自定义字段取值，JSON 对象
+	ReviewStatus string `json:"review_status,omitempty" gorm:"size:20;default:'';index"` // 在所属工作流中的审核状态，未提交时为空
 
 	// 关联关系
-	Uploader     *User          `json:"uploader,omitempty" gorm:"foreignKey:UploadedBy"`
-	Workflow     *WorkflowGroup `json:"workflow,omitempty" gorm:"foreignKey:WorkflowID"`
-	MaterialTags []MaterialTag  `json:"material_tags,omitempty" gorm:"foreignKey:MaterialID"`
+	Uploader     *User           `json:"uploader,omitempty" gorm:"foreignKey:UploadedBy"`
+	Workflow     *WorkflowGroup  `json:"workflow,omitempty" gorm:"foreignKey:WorkflowID"`
+	MaterialTags []MaterialTag   `json:"material_tags,omitempty" gorm:"foreignKey:MaterialID"`
+	Colors       []MaterialColor `json:"-" gorm:"foreignKey:MaterialID"`
+	Places       []MaterialPlace `json:"-" gorm:"foreignKey:MaterialID"`
+}
+
+// MaterialColor 素材主色，保存 Lab 分量用于按颜色相近程度检索
+type MaterialColor struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	MaterialID uint    `json:"material_id" gorm:"not null;index"`
+	Hex        string  `json:"hex" gorm:"not null;size:7"`
+	L          float64 `json:"l" gorm:"not null"`
+	A          float64 `json:"a" gorm:"not null"`
+	B          float64 `json:"b" gorm:"not null"`
+	Weight     float64 `json:"weight" gorm:"not null"` // 该颜色所占像素比例
+}
+
+// MaterialSearch 素材全文检索索引，由 services 包维护
+type MaterialSearch struct {
+	MaterialID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Document   string `gorm:"type:text"`                     // 参与检索的原文
+	Tokens     string `gorm:"type:tsvector;index:,type:gin"` // 分词后的词元
 }
 
 // MaterialResponse 用于返回给前端的素材信息，包含安全的用户信息
 type MaterialResponse struct {
-	ID               uint      `json:"id"`
-	Filename         string    `json:"filename"`
-	OriginalFilename string    `json:"original_filename"`
-	FilePath         string    `json:"file_path"`
-	FileSize         int64     `json:"file_size"`
-	FileType         string    `json:"file_type"`
-	MimeType         string    `json:"mime_type"`
-	Width            *int      `json:"width,omitempty"`
-	Height           *int      `json:"height,omitempty"`
-	Duration         *int      `json:"duration,omitempty"`
-	UploadedBy       uint      `json:"uploaded_by"`
-	WorkflowID       *uint     `json:"workflow_id,omitempty"`
-	UploadTime       time.Time `json:"upload_time"`
-	IsStarred        bool      `json:"is_starred"`
-	IsPublic         bool      `json:"is_public"`
-	ThumbnailPath    string    `json:"thumbnail_path,omitempty"`
+	ID               uint                   `json:"id"`
+	Filename         string                 `json:"filename"`
+	OriginalFilename string                 `json:"original_filename"`
+	FilePath         string                 `json:"file_path"`
+	FileSize         int64                  `json:"file_size"`
+	FileType         string                 `json:"file_type"`
+	MimeType         string                 `json:"mime_type"`
+	Width            *int                   `json:"width,omitempty"`
+	Height           *int                   `json:"height,omitempty"`
+	Duration         *int                   `json:"duration,omitempty"`
+	UploadedBy       uint                   `json:"uploaded_by"`
+	WorkflowID       *uint                  `json:"workflow_id,omitempty"`
+	UploadTime       time.Time              `json:"upload_time"`
+	IsStarred        bool                   `json:"is_starred"`
+	IsPublic         bool                   `json:"is_public"`
+	ThumbnailPath    string                 `json:"thumbnail_path,omitempty"`
+	ProcessedPath    string                 `json:"processed_path,omitempty"`
+	StripMetadata    *bool                  `json:"strip_metadata,omitempty"`
+	Palette          []string               `json:"palette,omitempty"`
+	SharpnessScore   *float64               `json:"sharpness_score,omitempty"`
+	ExposureScore    *float64               `json:"exposure_score,omitempty"`
+	StorageTier      string                 `json:"storage_tier"`
+	RestoreStatus    string                 `json:"restore_status,omitempty"`
+	TieredAt         *time.Time             `json:"tiered_at,omitempty"`
+	Encrypted        bool                   `json:"encrypted"`
+	TakenAt          *time.Time             `json:"taken_at,omitempty"`
+	Caption          string                 `json:"caption,omitempty"`
+	Description      string                 `json:"description,omitempty"`
+	CustomFields     map[string]interface{} `json:"custom_fields,omitempty"`
+	ReviewStatus     string                 `json:"review_status,omitempty"`
 
 	// 安全的关联关系
 	Uploader     *SafeUser      `json:"uploader,omitempty"`
 	Workflow     *WorkflowGroup `json:"workflow,omitempty"`
 	MaterialTags []MaterialTag  `json:"material_tags,omitempty"`
+
+	// 关键词检索时各字段的命中高亮
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// CustomFieldValues 解析自定义字段取值
+func (m *Material) CustomFieldValues() map[string]interface{} {
+	if m.CustomFields == "" {
+		return nil
+	}
+	values := make(map[string]interface{})
+	_ = json.Unmarshal([]byte(m.CustomFields), &values)
+	return values
 }
 
 // ToMaterialResponse 将 Material 转换为 MaterialResponse
@@ -72,10 +141,27 @@ func (m *Material) ToMaterialResponse() *MaterialResponse {
 		IsStarred:        m.IsStarred,
 		IsPublic:         m.IsPublic,
 		ThumbnailPath:    m.ThumbnailPath,
+		ProcessedPath:    m.ProcessedPath,
+		StripMetadata:    m.StripMetadata,
+		SharpnessScore:   m.SharpnessScore,
+		ExposureScore:    m.ExposureScore,
+		StorageTier:      m.StorageTier,
+		RestoreStatus:    m.RestoreStatus,
+		TieredAt:         m.TieredAt,
+		Encrypted:        m.Encrypted,
+		TakenAt:          m.TakenAt,
+		Caption:          m.Caption,
+		Description:      m.Description,
+		CustomFields:     m.CustomFieldValues(),
+		ReviewStatus:     m.ReviewStatus,
 		Workflow:         m.Workflow,
 		MaterialTags:     m.MaterialTags,
 	}
 
+	if m.Palette != "" {
+		response.Palette = strings.Split(m.Palette, ",")
+	}
+
 	// 安全地转换用户信息
 	if m.Uploader != nil {
 		response.Uploader = m.Uploader.ToSafeUser()
@@ -83,3 +169,57 @@ func (m *Material) ToMaterialResponse() *MaterialResponse {
 
 	return response
 }
+
+// PublicMaterialResponse 提供给未登录访问者的素材信息，不包含上传者联系方式等内部信息
+type PublicMaterialResponse struct {
+	ID               uint        `json:"id"`
+	OriginalFilename string      `json:"original_filename"`
+	FilePath         string      `json:"file_path,omitempty"`
+	FileSize         int64       `json:"file_size"`
+	FileType         string      `json:"file_type"`
+	MimeType         string      `json:"mime_type"`
+	Width            *int        `json:"width,omitempty"`
+	Height           *int        `json:"height,omitempty"`
+	Duration         *int        `json:"duration,omitempty"`
+	ThumbnailPath    string      `json:"thumbnail_path,omitempty"`
+	TakenAt          *time.Time  `json:"taken_at,omitempty"`
+	Caption          string      `json:"caption,omitempty"`
+	Description      string      `json:"description,omitempty"`
+	UploaderName     string      `json:"uploader_name,omitempty"`
+	Tags             []PublicTag `json:"tags,omitempty"`
+}
+
+// PublicTag 公开素材上的标签
+type PublicTag struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// ToPublicMaterialResponse 将 Material 转换为 PublicMaterialResponse
+func (m *Material) ToPublicMaterialResponse() *PublicMaterialResponse {
+	response := &PublicMaterialResponse{
+		ID:               m.ID,
+		OriginalFilename: m.OriginalFilename,
+		FilePath:         m.FilePath,
+		FileSize:         m.FileSize,
+		FileType:         m.FileType,
+		MimeType:         m.MimeType,
+		Width:            m.Width,
+		Height:           m.Height,
+		Duration:         m.Duration,
+		ThumbnailPath:    m.ThumbnailPath,
+		TakenAt:          m.TakenAt,
+		Caption:          m.Caption,
+		Description:      m.Description,
+	}
+	if m.Uploader != nil {
+		response.UploaderName = m.Uploader.Username
+	}
+	for _, materialTag := range m.MaterialTags {
+		if materialTag.Tag.ID != 0 {
+			response.Tags = append(response.Tags, PublicTag{ID: materialTag.Tag.ID, Name: materialTag.Tag.Name, Color: materialTag.Tag.Color})
+		}
+	}
+	return response
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationWorkflowMember   = "workflow_member"   // 被添加为工作流成员
+	NotificationMention          = "mention"           // 在评论中被 @
+	NotificationMaterialReviewed = "material_reviewed" // 自己的素材审核通过或被退回
+	NotificationInviteUsed       = "invite_used"       // 自己生成的邀请码被使用
+	NotificationPipelineDone     = "pipeline_done"     // 工作流处理流程执行完成
+)
+
+// NotificationTypeLabels 通知类型及其名称，用于通知偏好设置
+var NotificationTypeLabels = map[string]string{
+	NotificationWorkflowMember:   "被添加到工作流",
+	NotificationMention:          "评论中提到我",
+	NotificationMaterialReviewed: "素材审核结果",
+	NotificationInviteUsed:       "邀请码被使用",
+	NotificationPipelineDone:     "工作流处理完成",
+}
+
+// Notification 站内通知
+type Notification struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index:idx_notification_user"`
+	Type       string     `json:"type" gorm:"not null;size:30"`
+	Title      string     `json:"title" gorm:"not null;size:200"`
+	Content    string     `json:"content,omitempty" gorm:"type:text"`
+	ActorID    *uint      `json:"actor_id,omitempty"` // 触发通知的用户
+	MaterialID *uint      `json:"material_id,omitempty"`
+	WorkflowID *uint      `json:"workflow_id,omitempty"`
+	CommentID  *uint      `json:"comment_id,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty" gorm:"index:idx_notification_user"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// 关联关系
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// NotificationPreference 用户对某类通知的开关，没有记录时默认开启
+type NotificationPreference struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	UserID  uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_notification_preference"`
+	Type    string `json:"type" gorm:"not null;size:30;uniqueIndex:idx_notification_preference"`
+	Enabled bool   `json:"enabled"`
+}
+
+// NotificationResponse 用于返回给前端的通知信息
+type NotificationResponse struct {
+	ID         uint       `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content,omitempty"`
+	ActorID    *uint      `json:"actor_id,omitempty"`
+	MaterialID *uint      `json:"material_id,omitempty"`
+	WorkflowID *uint      `json:"workflow_id,omitempty"`
+	CommentID  *uint      `json:"comment_id,omitempty"`
+	Read       bool       `json:"read"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Actor      *SafeUser  `json:"actor,omitempty"`
+}
+
+// ToNotificationResponse 将 Notification 转换为 NotificationResponse
+func (n *Notification) ToNotificationResponse() *NotificationResponse {
+	response := &NotificationResponse{
+		ID:         n.ID,
+		Type:       n.Type,
+		Title:      n.Title,
+		Content:    n.Content,
+		ActorID:    n.ActorID,
+		MaterialID: n.MaterialID,
+		WorkflowID: n.WorkflowID,
+		CommentID:  n.CommentID,
+		Read:       n.ReadAt != nil,
+		ReadAt:     n.ReadAt,
+		CreatedAt:  n.CreatedAt,
+	}
+	if n.Actor != nil {
+		response.Actor = n.Actor.ToSafeUser()
+	}
+	return response
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// 处理步骤类型
+const (
+	PipelineStepResize        = "resize"         // 按最大宽高等比缩小图片
+	PipelineStepConvert       = "convert"        // 转换图片格式
+	PipelineStepWatermark     = "watermark"      // 叠加图片水印
+	PipelineStepAutoTag       = "auto_tag"       // 自动添加标签
+	PipelineStepTranscode     = "transcode"      // 视频转码
+	PipelineStepStripMetadata = "strip_metadata" // 去除公开文件的敏感元数据
+	PipelineStepNotify        = "notify"         // 发送站内通知
+)
+
+// 处理的触发方式
+const (
+	PipelineTriggerUpload = "upload" // 上传到工作流时自动执行
+	PipelineTriggerManual = "manual" // 对已有素材手动执行
+)
+
+// 处理及步骤的状态
+const (
+	PipelineStatusRunning   = "running"
+	PipelineStatusSucceeded = "succeeded"
+	PipelineStatusFailed    = "failed"
+	PipelineStatusSkipped   = "skipped" // 步骤不适用于该素材，或前序步骤失败
+)
+
+// PipelineStep 处理步骤，各类型只使用与其相关的字段
+type PipelineStep struct {
+	Type string `json:"type"`
+
+	// resize：最大宽高（像素），transcode 也使用 MaxHeight
+	MaxWidth  int `json:"max_width,omitempty"`
+	MaxHeight int `json:"max_height,omitempty"`
+	// convert：jpg、png、gif、bmp、tiff；transcode：mp4、webm
+	Format string `json:"format,omitempty"`
+	// convert：JPEG 质量（1-100）
+	Quality int `json:"quality,omitempty"`
+	// watermark：作为水印的图片素材、位置（top_left、top_right、bottom_left、bottom_right、center）、
+	// 不透明度（0-1）与水印宽度占图片宽度的比例（0-1）
+	WatermarkMaterialID uint    `json:"watermark_material_id,omitempty"`
+	Position            string  `json:"position,omitempty"`
+	Opacity             float64 `json:"opacity,omitempty"`
+	Scale               float64 `json:"scale,omitempty"`
+	// auto_tag：为所有素材添加的标签，以及为可能的废片添加的标签
+	TagIDs          []uint `json:"tag_ids,omitempty"`
+	RejectableTagID uint   `json:"rejectable_tag_id,omitempty"`
+	// transcode：视频质量（CRF，越小质量越高）
+	CRF int `json:"crf,omitempty"`
+	// notify：接收通知的成员角色与用户，均未设置时通知上传者
+	Roles   []string `json:"roles,omitempty"`
+	UserIDs []uint   `json:"user_ids,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// PipelineConfig 工作流处理流程，保存在 WorkflowGroup.Config 中
+type PipelineConfig struct {
+	Steps []PipelineStep `json:"steps"`
+}
+
+// PipelineRun 一次对素材执行的处理
+type PipelineRun struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	WorkflowID  uint       `json:"workflow_id" gorm:"not null;index"`
+	MaterialID  uint       `json:"material_id" gorm:"not null;index"`
+	Trigger     string     `json:"trigger" gorm:"not null;size:20"`
+	Status      string     `json:"status" gorm:"not null;size:20"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	TriggeredBy *uint      `json:"triggered_by,omitempty"` // 手动执行的用户
+	StartedAt   time.Time  `json:"started_at" gorm:"autoCreateTime"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+
+	// 关联关系
+	Steps []PipelineStepResult `json:"steps,omitempty" gorm:"foreignKey:RunID"`
+}
+
+// PipelineStepResult 处理中单个步骤的结果
+type PipelineStepResult struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	RunID      uint   `json:"run_id" gorm:"not null;index"`
+	Position   int    `json:"position"` // 步骤在流程中的序号，从 0 开始
+	Type       string `json:"type" gorm:"not null;size:30"`
+	Status     string `json:"status" gorm:"not null;size:20"`
+	Message    string `json:"message,omitempty" gorm:"type:text"`
+	DurationMs int64  `json:"duration_ms"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Place 管理员定义的命名地点，范围为多边形，拍摄位置落在其中的素材会被自动标记
+type Place struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null;size:100;uniqueIndex"`
+	Description string `json:"description,omitempty"`
+	Polygon     string `json:"-" gorm:"type:text;not null"` // 顶点列表 [[经度, 纬度], ...]，JSON
+	// 外接矩形，用于快速预筛选
+	MinLat    float64   `json:"-"`
+	MinLng    float64   `json:"-"`
+	MaxLat    float64   `json:"-"`
+	MaxLng    float64   `json:"-"`
+	CreatedBy uint      `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// MaterialPlace 素材所在的命名地点
+type MaterialPlace struct {
+	ID         uint `json:"id" gorm:"primaryKey"`
+	MaterialID uint `json:"material_id" gorm:"not null;uniqueIndex:idx_material_place"`
+	PlaceID    uint `json:"place_id" gorm:"not null;uniqueIndex:idx_material_place;index"`
+
+	// 关联关系
+	Place *Place `json:"place,omitempty" gorm:"foreignKey:PlaceID"`
+}
+
+// PlaceResponse 用于返回给前端的地点信息
+type PlaceResponse struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Polygon     [][2]float64 `json:"polygon"`
+	CreatedBy   uint         `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Vertices 解析多边形顶点
+func (p *Place) Vertices() [][2]float64 {
+	var vertices [][2]float64
+	_ = json.Unmarshal([]byte(p.Polygon), &vertices)
+	return vertices
+}
+
+// ToPlaceResponse 将 Place 转换为 PlaceResponse
+func (p *Place) ToPlaceResponse() *PlaceResponse {
+	return &PlaceResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Polygon:     p.Vertices(),
+		CreatedBy:   p.CreatedBy,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
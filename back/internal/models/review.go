@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// 工作流素材的审核状态，空字符串表示尚未提交审核
+const (
+	ReviewStatusSubmitted = "submitted" // 已提交，等待审核
+	ReviewStatusInReview  = "in_review" // 审核中
+	ReviewStatusApproved  = "approved"  // 审核通过
+	ReviewStatusRejected  = "rejected"  // 被退回，需注明原因
+	ReviewStatusPublished = "published" // 已发布
+)
+
+// ReviewStatusLabels 审核状态及其名称
+var ReviewStatusLabels = map[string]string{
+	ReviewStatusSubmitted: "已提交",
+	ReviewStatusInReview:  "审核中",
+	ReviewStatusApproved:  "已通过",
+	ReviewStatusRejected:  "已退回",
+	ReviewStatusPublished: "已发布",
+}
+
+// ReviewStatuses 审核状态按流程先后排列
+var ReviewStatuses = []string{
+	ReviewStatusSubmitted,
+	ReviewStatusInReview,
+	ReviewStatusApproved,
+	ReviewStatusRejected,
+	ReviewStatusPublished,
+}
+
+// MaterialReviewLog 素材审核状态的变更记录
+type MaterialReviewLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	MaterialID uint      `json:"material_id" gorm:"not null;index"`
+	WorkflowID *uint     `json:"workflow_id,omitempty" gorm:"index"`
+	FromStatus string    `json:"from_status" gorm:"size:20"`
+	ToStatus   string    `json:"to_status" gorm:"size:20"`
+	Reason     string    `json:"reason,omitempty" gorm:"type:text"`
+	ActorID    uint      `json:"actor_id" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// 关联关系
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// MaterialReviewLogResponse 用于返回给前端的审核记录
+type MaterialReviewLogResponse struct {
+	ID         uint      `json:"id"`
+	MaterialID uint      `json:"material_id"`
+	WorkflowID *uint     `json:"workflow_id,omitempty"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason,omitempty"`
+	ActorID    uint      `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Actor      *SafeUser `json:"actor,omitempty"`
+}
+
+// ToMaterialReviewLogResponse 将 MaterialReviewLog 转换为 MaterialReviewLogResponse
+func (l *MaterialReviewLog) ToMaterialReviewLogResponse() *MaterialReviewLogResponse {
+	response := &MaterialReviewLogResponse{
+		ID:         l.ID,
+		MaterialID: l.MaterialID,
+		WorkflowID: l.WorkflowID,
+		FromStatus: l.FromStatus,
+		ToStatus:   l.ToStatus,
+		Reason:     l.Reason,
+		ActorID:    l.ActorID,
+		CreatedAt:  l.CreatedAt,
+	}
+	if l.Actor != nil {
+		response.Actor = l.Actor.ToSafeUser()
+	}
+	return response
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 分享链接的目标类型
+const (
+	ShareTargetMaterial = "material"
+	ShareTargetAlbum    = "album"
+	ShareTargetWorkflow = "workflow" // 工作流中的全部素材或选定的部分素材
+)
+
+// ShareLink 免登录访问的分享链接
+type ShareLink struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Token      string `json:"token" gorm:"uniqueIndex;not null;size:64"`
+	TargetType string `json:"target_type" gorm:"not null;size:20;index:idx_share_target"`
+	TargetID   uint   `json:"target_id" gorm:"not null;index:idx_share_target"`
+	// 分享工作流时选定的素材ID，JSON 数组，为空表示工作流中的全部素材
+	MaterialIDs   string     `json:"-" gorm:"type:text"`
+	PasswordHash  string     `json:"-" gorm:"size:255"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	AllowDownload bool       `json:"allow_download" gorm:"default:false"`
+	ViewCount     int64      `json:"view_count" gorm:"default:0"`
+	DownloadCount int64      `json:"download_count" gorm:"default:0"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy     uint       `json:"created_by" gorm:"not null;index"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// 关联关系
+	Creator *User `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+}
+
+// ShareLinkResponse 用于返回给分享者的链接信息
+type ShareLinkResponse struct {
+	ID               uint       `json:"id"`
+	Token            string     `json:"token"`
+	TargetType       string     `json:"target_type"`
+	TargetID         uint       `json:"target_id"`
+	MaterialIDs      []uint     `json:"material_ids,omitempty"`
+	PasswordRequired bool       `json:"password_required"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	AllowDownload    bool       `json:"allow_download"`
+	ViewCount        int64      `json:"view_count"`
+	DownloadCount    int64      `json:"download_count"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy        uint       `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// SelectedMaterialIDs 解析分享工作流时选定的素材ID
+func (s *ShareLink) SelectedMaterialIDs() []uint {
+	var ids []uint
+	if s.MaterialIDs != "" {
+		_ = json.Unmarshal([]byte(s.MaterialIDs), &ids)
+	}
+	return ids
+}
+
+// IsActive 链接未撤销且未过期
+func (s *ShareLink) IsActive() bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || time.Now().Before(*s.ExpiresAt))
+}
+
+// ToShareLinkResponse 将 ShareLink 转换为 ShareLinkResponse
+func (s *ShareLink) ToShareLinkResponse() *ShareLinkResponse {
+	return &ShareLinkResponse{
+		ID:               s.ID,
+		Token:            s.Token,
+		TargetType:       s.TargetType,
+		TargetID:         s.TargetID,
+		MaterialIDs:      s.SelectedMaterialIDs(),
+		PasswordRequired: s.PasswordHash != "",
+		ExpiresAt:        s.ExpiresAt,
+		AllowDownload:    s.AllowDownload,
+		ViewCount:        s.ViewCount,
+		DownloadCount:    s.DownloadCount,
+		RevokedAt:        s.RevokedAt,
+		CreatedBy:        s.CreatedBy,
+		CreatedAt:        s.CreatedAt,
+	}
+}
@@ -1,21 +1,53 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
+// 工作流类型，决定处理流程适用的素材
+const (
+	WorkflowTypeImageProcessing = "image_processing" // 只处理图片
+	WorkflowTypeVideoProcessing = "video_processing" // 只处理视频
+	WorkflowTypeFileConversion  = "file_conversion"
+	WorkflowTypeBatchOperation  = "batch_operation" // 上传时不自动处理，只能手动执行
+	WorkflowTypeCustom          = "custom"
+)
+
+// WorkflowTypeLabels 工作流类型及其名称
+var WorkflowTypeLabels = map[string]string{
+	WorkflowTypeImageProcessing: "图片处理",
+	WorkflowTypeVideoProcessing: "视频处理",
+	WorkflowTypeFileConversion:  "文件转换",
+	WorkflowTypeBatchOperation:  "批量操作",
+	WorkflowTypeCustom:          "自定义",
+}
+
+// 工作流状态
+const (
+	WorkflowStatusActive   = "active"
+	WorkflowStatusArchived = "archived" // 已归档，工作流及其素材只读
+)
+
 type WorkflowGroup struct {
-	ID          uint       `json:"id" gorm:"primaryKey"`
-	Name        string     `json:"name" gorm:"not null;size:100"`
-	Description string     `json:"description,omitempty"`
-	Type        string     `json:"type" gorm:"default:'custom';size:50"`  // image_processing, video_processing, file_conversion, batch_operation, custom
-	Color       string     `json:"color" gorm:"default:'#409EFF';size:7"` // 十六进制颜色
-	IsActive    bool       `json:"is_active" gorm:"default:true"`
-	Config      string     `json:"config,omitempty" gorm:"type:text"`      // JSON配置
-	Status      string     `json:"status" gorm:"default:'active';size:20"` // active, archived
-	CreatedBy   uint       `json:"created_by" gorm:"not null"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null;size:100"`
+	NamePattern string `json:"name_pattern,omitempty" gorm:"size:100"` // 名称模板，克隆时据此生成新名称
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type" gorm:"default:'custom';size:50"`  // image_processing, video_processing, file_conversion, batch_operation, custom
+	Color       string `json:"color" gorm:"default:'#409EFF';size:7"` // 十六进制颜色
+	IsActive    bool   `json:"is_active" gorm:"default:true"`
+	Config      string `json:"config,omitempty" gorm:"type:text"`      // 处理流程配置（PipelineConfig JSON）
+	Status      string `json:"status" gorm:"default:'active';size:20"` // active, archived
+	DefaultTags string `json:"-" gorm:"type:text"`                     // 上传到工作流的素材自动添加的标签ID，JSON 数组
+	// 公开的文件是否去除 GPS、设备序列号等敏感元数据
+	StripMetadata bool `json:"strip_metadata" gorm:"default:false"`
+	// 素材自定义字段定义，JSON 数组
+	CustomFieldSchema string     `json:"-" gorm:"type:text"`
+	CreatedBy         uint       `json:"created_by" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`              // 归档时间
+	Deadline          *time.Time `json:"deadline,omitempty" gorm:"index"` // 截止时间，到期后自动归档
 
 	// 关联关系
 	Creator   *User            `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
@@ -23,11 +55,55 @@ type WorkflowGroup struct {
 	Members   []WorkflowMember `json:"members,omitempty" gorm:"foreignKey:WorkflowID"`
 }
 
+// 工作流成员角色
+const (
+	WorkflowRoleAdmin        = "admin"        // 工作流管理员
+	WorkflowRoleReviewer     = "reviewer"     // 审核
+	WorkflowRolePhotographer = "photographer" // 摄影，添加成员时的默认角色
+	WorkflowRoleViewer       = "viewer"       // 只读
+	WorkflowRoleMember       = "member"       // 早期版本的默认角色，权限等同摄影
+)
+
+// 工作流权限
+const (
+	WorkflowPermView   = "view"   // 查看工作流及其全部素材（含未公开的素材）
+	WorkflowPermUpload = "upload" // 上传素材或将素材移入工作流
+	WorkflowPermReview = "review" // 修改素材审核状态
+	WorkflowPermManage = "manage" // 修改工作流、管理成员、编辑工作流中的素材、分享与冷存储
+)
+
+// WorkflowRolePermissions 各角色拥有的权限；工作流创建者视为管理员，删除工作流仅限创建者与系统管理员
+var WorkflowRolePermissions = map[string][]string{
+	WorkflowRoleAdmin:        {WorkflowPermView, WorkflowPermUpload, WorkflowPermReview, WorkflowPermManage},
+	WorkflowRoleReviewer:     {WorkflowPermView, WorkflowPermUpload, WorkflowPermReview},
+	WorkflowRolePhotographer: {WorkflowPermView, WorkflowPermUpload},
+	WorkflowRoleViewer:       {WorkflowPermView},
+	WorkflowRoleMember:       {WorkflowPermView, WorkflowPermUpload},
+}
+
+// WorkflowRoleLabels 可分配的成员角色及其名称
+var WorkflowRoleLabels = map[string]string{
+	WorkflowRoleAdmin:        "管理员",
+	WorkflowRoleReviewer:     "审核",
+	WorkflowRolePhotographer: "摄影",
+	WorkflowRoleViewer:       "只读",
+}
+
+// WorkflowRoleAllows 角色是否拥有某项权限
+func WorkflowRoleAllows(role, permission string) bool {
+	for _, p := range WorkflowRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
 type WorkflowMember struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
 	WorkflowID uint      `json:"workflow_id" gorm:"not null"`
 	UserID     uint      `json:"user_id" gorm:"not null"`
-	Role       string    `json:"role" gorm:"not null;size:20"` // photographer, reviewer, admin
+	Role       string    `json:"role" gorm:"not null;size:20"` // admin, reviewer, photographer, viewer
 	JoinedAt   time.Time `json:"joined_at" gorm:"autoCreateTime"`
 
 	// 关联关系
@@ -35,19 +111,103 @@ type WorkflowMember struct {
 	User     *User          `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
 }
 
+// 自定义字段类型
+const (
+	CustomFieldText   = "text"
+	CustomFieldNumber = "number"
+	CustomFieldDate   = "date"   // YYYY-MM-DD
+	CustomFieldEnum   = "enum"   // 取值限定在 Options 中
+	CustomFieldPerson = "person" // 用户ID
+)
+
+// CustomFieldDefinition 工作流为其素材定义的自定义字段
+type CustomFieldDefinition struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// MemberRole 用户在工作流中的角色（需预加载 Members），创建者视为管理员，非成员返回空字符串
+func (w *WorkflowGroup) MemberRole(userID uint) string {
+	if w.CreatedBy == userID {
+		return WorkflowRoleAdmin
+	}
+	for _, member := range w.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// IsArchived 工作流是否已归档；截止时间已过但尚未被自动归档的也视为已归档
+func (w *WorkflowGroup) IsArchived() bool {
+	if w.Status == WorkflowStatusArchived {
+		return true
+	}
+	return w.Deadline != nil && !w.Deadline.After(time.Now())
+}
+
+// PipelineAppliesTo 工作流的处理流程是否适用于该类型的素材
+func (w *WorkflowGroup) PipelineAppliesTo(fileType string) bool {
+	switch w.Type {
+	case WorkflowTypeImageProcessing:
+		return fileType == "image"
+	case WorkflowTypeVideoProcessing:
+		return fileType == "video"
+	}
+	return true
+}
+
+// Pipeline 解析处理流程配置，未配置或无法解析时返回空流程
+func (w *WorkflowGroup) Pipeline() PipelineConfig {
+	var pipeline PipelineConfig
+	if w.Config != "" {
+		_ = json.Unmarshal([]byte(w.Config), &pipeline)
+	}
+	return pipeline
+}
+
+// DefaultTagIDs 解析默认标签ID
+func (w *WorkflowGroup) DefaultTagIDs() []uint {
+	ids := []uint{}
+	if w.DefaultTags != "" {
+		_ = json.Unmarshal([]byte(w.DefaultTags), &ids)
+	}
+	return ids
+}
+
+// CustomFields 解析自定义字段定义
+func (w *WorkflowGroup) CustomFields() []CustomFieldDefinition {
+	fields := []CustomFieldDefinition{}
+	if w.CustomFieldSchema != "" {
+		_ = json.Unmarshal([]byte(w.CustomFieldSchema), &fields)
+	}
+	return fields
+}
+
 // WorkflowGroupResponse 用于返回给前端的工作流信息，包含安全的用户信息
 type WorkflowGroupResponse struct {
-	ID          uint       `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description,omitempty"`
-	Type        string     `json:"type"`
-	Color       string     `json:"color"`
-	IsActive    bool       `json:"is_active"`
-	Config      string     `json:"config,omitempty"`
-	Status      string     `json:"status"`
-	CreatedBy   uint       `json:"created_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	ID            uint                    `json:"id"`
+	Name          string                  `json:"name"`
+	NamePattern   string                  `json:"name_pattern,omitempty"`
+	Description   string                  `json:"description,omitempty"`
+	Type          string                  `json:"type"`
+	Color         string                  `json:"color"`
+	IsActive      bool                    `json:"is_active"`
+	Config        string                  `json:"config,omitempty"`
+	Status        string                  `json:"status"`
+	StripMetadata bool                    `json:"strip_metadata"`
+	CustomFields  []CustomFieldDefinition `json:"custom_fields"`
+	DefaultTagIDs []uint                  `json:"default_tag_ids"`
+	MyRole        string                  `json:"my_role,omitempty"` // 当前用户在工作流中的角色
+	ReadOnly      bool                    `json:"read_only"`         // 已归档，不能上传、修改或删除素材
+	CreatedBy     uint                    `json:"created_by"`
+	CreatedAt     time.Time               `json:"created_at"`
+	EndedAt       *time.Time              `json:"ended_at,omitempty"`
+	Deadline      *time.Time              `json:"deadline,omitempty"`
 
 	// 安全的关联关系
 	Creator   *SafeUser                `json:"creator,omitempty"`
@@ -71,18 +231,24 @@ type WorkflowMemberResponse struct {
 // ToWorkflowGroupResponse 将 WorkflowGroup 转换为 WorkflowGroupResponse
 func (w *WorkflowGroup) ToWorkflowGroupResponse() *WorkflowGroupResponse {
 	response := &WorkflowGroupResponse{
-		ID:          w.ID,
-		Name:        w.Name,
-		Description: w.Description,
-		Type:        w.Type,
-		Color:       w.Color,
-		IsActive:    w.IsActive,
-		Config:      w.Config,
-		Status:      w.Status,
-		CreatedBy:   w.CreatedBy,
-		CreatedAt:   w.CreatedAt,
-		EndedAt:     w.EndedAt,
-		Materials:   w.Materials,
+		ID:            w.ID,
+		Name:          w.Name,
+		NamePattern:   w.NamePattern,
+		Description:   w.Description,
+		Type:          w.Type,
+		Color:         w.Color,
+		IsActive:      w.IsActive,
+		Config:        w.Config,
+		Status:        w.Status,
+		StripMetadata: w.StripMetadata,
+		CustomFields:  w.CustomFields(),
+		DefaultTagIDs: w.DefaultTagIDs(),
+		ReadOnly:      w.IsArchived(),
+		CreatedBy:     w.CreatedBy,
+		CreatedAt:     w.CreatedAt,
+		EndedAt:       w.EndedAt,
+		Deadline:      w.Deadline,
+		Materials:     w.Materials,
 	}
 
 	// 安全地转换创建者信息
@@ -123,9 +289,11 @@ func (w *WorkflowMember) ToWorkflowMemberResponse() *WorkflowMemberResponse {
 			Name:        w.Workflow.Name,
 			Description: w.Workflow.Description,
 			Status:      w.Workflow.Status,
+			ReadOnly:    w.Workflow.IsArchived(),
 			CreatedBy:   w.Workflow.CreatedBy,
 			CreatedAt:   w.Workflow.CreatedAt,
 			EndedAt:     w.Workflow.EndedAt,
+			Deadline:    w.Workflow.Deadline,
 		}
 	}
 
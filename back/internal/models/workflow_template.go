@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WorkflowTemplateMember 模板中的成员及其角色
+type WorkflowTemplateMember struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// WorkflowTemplate 工作流模板，保存创建工作流所需的设置，不包含素材
+// 模板默认只有创建者可见，系统管理员发布后所有用户都可使用
+type WorkflowTemplate struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	Name          string `json:"name" gorm:"not null;size:100"`         // 模板名称
+	NamePattern   string `json:"name_pattern" gorm:"not null;size:100"` // 工作流名称模板，可使用 {year}、{month}、{date}、{semester}
+	Description   string `json:"description,omitempty"`
+	Type          string `json:"type" gorm:"default:'custom';size:50"`
+	Color         string `json:"color" gorm:"default:'#409EFF';size:7"`
+	Config        string `json:"config,omitempty" gorm:"type:text"` // 处理流程配置
+	StripMetadata bool   `json:"strip_metadata" gorm:"default:false"`
+	// 素材自定义字段定义、默认标签ID与成员角色，均为 JSON 数组
+	CustomFieldSchema string    `json:"-" gorm:"type:text"`
+	DefaultTags       string    `json:"-" gorm:"type:text"`
+	Members           string    `json:"-" gorm:"type:text"`
+	IsPublished       bool      `json:"is_published" gorm:"default:false;index"`
+	CreatedBy         uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt         time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 关联关系
+	Creator *User `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+}
+
+// WorkflowTemplateResponse 用于返回给前端的模板信息
+type WorkflowTemplateResponse struct {
+	ID            uint                     `json:"id"`
+	Name          string                   `json:"name"`
+	NamePattern   string                   `json:"name_pattern"`
+	Description   string                   `json:"description,omitempty"`
+	Type          string                   `json:"type"`
+	Color         string                   `json:"color"`
+	Config        string                   `json:"config,omitempty"`
+	StripMetadata bool                     `json:"strip_metadata"`
+	CustomFields  []CustomFieldDefinition  `json:"custom_fields"`
+	DefaultTagIDs []uint                   `json:"default_tag_ids"`
+	Members       []WorkflowTemplateMember `json:"members"`
+	IsPublished   bool                     `json:"is_published"`
+	CreatedBy     uint                     `json:"created_by"`
+	CreatedAt     time.Time                `json:"created_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+	Creator       *SafeUser                `json:"creator,omitempty"`
+}
+
+// CustomFields 解析自定义字段定义
+func (t *WorkflowTemplate) CustomFields() []CustomFieldDefinition {
+	fields := []CustomFieldDefinition{}
+	if t.CustomFieldSchema != "" {
+		_ = json.Unmarshal([]byte(t.CustomFieldSchema), &fields)
+	}
+	return fields
+}
+
+// DefaultTagIDs 解析默认标签ID
+func (t *WorkflowTemplate) DefaultTagIDs() []uint {
+	ids := []uint{}
+	if t.DefaultTags != "" {
+		_ = json.Unmarshal([]byte(t.DefaultTags), &ids)
+	}
+	return ids
+}
+
+// MemberRoles 解析成员及其角色
+func (t *WorkflowTemplate) MemberRoles() []WorkflowTemplateMember {
+	members := []WorkflowTemplateMember{}
+	if t.Members != "" {
+		_ = json.Unmarshal([]byte(t.Members), &members)
+	}
+	return members
+}
+
+// ToWorkflowTemplateResponse 将 WorkflowTemplate 转换为 WorkflowTemplateResponse
+func (t *WorkflowTemplate) ToWorkflowTemplateResponse() *WorkflowTemplateResponse {
+	response := &WorkflowTemplateResponse{
+		ID:            t.ID,
+		Name:          t.Name,
+		NamePattern:   t.NamePattern,
+		Description:   t.Description,
+		Type:          t.Type,
+		Color:         t.Color,
+		Config:        t.Config,
+		StripMetadata: t.StripMetadata,
+		CustomFields:  t.CustomFields(),
+		DefaultTagIDs: t.DefaultTagIDs(),
+		Members:       t.MemberRoles(),
+		IsPublished:   t.IsPublished,
+		CreatedBy:     t.CreatedBy,
+		CreatedAt:     t.CreatedAt,
+		UpdatedAt:     t.UpdatedAt,
+	}
+	if t.Creator != nil {
+		response.Creator = t.Creator.ToSafeUser()
+	}
+	return response
+}
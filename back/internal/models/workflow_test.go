@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestWorkflowRoleAllows(t *testing.T) {
+	permissions := []string{WorkflowPermView, WorkflowPermUpload, WorkflowPermReview, WorkflowPermManage}
+	tests := []struct {
+		role  string
+		allow []bool // 依次为 view、upload、review、manage
+	}{
+		{WorkflowRoleAdmin, []bool{true, true, true, true}},
+		{WorkflowRoleReviewer, []bool{true, true, true, false}},
+		{WorkflowRolePhotographer, []bool{true, true, false, false}},
+		{WorkflowRoleMember, []bool{true, true, false, false}},
+		{WorkflowRoleViewer, []bool{true, false, false, false}},
+		{"", []bool{false, false, false, false}},
+		{"owner", []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			for i, permission := range permissions {
+				if got := WorkflowRoleAllows(tt.role, permission); got != tt.allow[i] {
+					t.Errorf("%s 权限: 得到 %v，期望 %v", permission, got, tt.allow[i])
+				}
+			}
+			if WorkflowRoleAllows(tt.role, "delete") {
+				t.Error("未知权限不应被允许")
+			}
+		})
+	}
+}
+
+func TestWorkflowMemberRole(t *testing.T) {
+	workflow := &WorkflowGroup{
+		CreatedBy: 1,
+		Members: []WorkflowMember{
+			{UserID: 1, Role: WorkflowRoleViewer},
+			{UserID: 2, Role: WorkflowRoleReviewer},
+			{UserID: 3, Role: WorkflowRoleMember},
+		},
+	}
+	tests := []struct {
+		userID uint
+		want   string
+	}{
+		{1, WorkflowRoleAdmin}, // 创建者视为管理员，与成员记录中的角色无关
+		{2, WorkflowRoleReviewer},
+		{3, WorkflowRoleMember},
+		{4, ""},
+	}
+	for _, tt := range tests {
+		if got := workflow.MemberRole(tt.userID); got != tt.want {
+			t.Errorf("用户 %d 的角色为 %q，期望 %q", tt.userID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiter 限制密码等凭据的尝试次数：同一来源在时间窗口内失败次数达到上限后，
+// 需等到窗口结束才能再次尝试，成功后清除失败记录
+type AttemptLimiter struct {
+	mu       sync.Mutex
+	attempts map[string]attemptRecord
+	max      int
+	window   time.Duration
+}
+
+type attemptRecord struct {
+	Failures int
+	ResetAt  time.Time
+}
+
+// 清理过期记录的阈值，避免记录无限增长
+const attemptLimiterPruneSize = 1024
+
+// NewAttemptLimiter 创建尝试次数限制器，window 内最多失败 max 次
+func NewAttemptLimiter(max int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		attempts: make(map[string]attemptRecord),
+		max:      max,
+		window:   window,
+	}
+}
+
+// SharePasswordLimiter 分享链接访问密码的尝试限制，按链接与访问者 IP 计数
+var SharePasswordLimiter = NewAttemptLimiter(5, 15*time.Minute)
+
+// Allow 返回 key 当前能否尝试，不能时同时返回需等待的时长
+func (l *AttemptLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, exists := l.attempts[key]
+	if !exists {
+		return true, 0
+	}
+	if !now.Before(record.ResetAt) {
+		delete(l.attempts, key)
+		return true, 0
+	}
+	if record.Failures >= l.max {
+		return false, record.ResetAt.Sub(now)
+	}
+	return true, 0
+}
+
+// Fail 记录一次失败的尝试，时间窗口从第一次失败开始计算
+func (l *AttemptLimiter) Fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.attempts) >= attemptLimiterPruneSize {
+		for k, record := range l.attempts {
+			if !now.Before(record.ResetAt) {
+				delete(l.attempts, k)
+			}
+		}
+	}
+
+	record, exists := l.attempts[key]
+	if !exists || !now.Before(record.ResetAt) {
+		record = attemptRecord{ResetAt: now.Add(l.window)}
+	}
+	record.Failures++
+	l.attempts[key] = record
+}
+
+// Reset 清除 key 的失败记录
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewAttemptLimiter(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a", start); !ok {
+			t.Fatalf("第 %d 次尝试不应被拒绝", i+1)
+		}
+		limiter.Fail("a", start.Add(time.Duration(i)*time.Second))
+	}
+
+	ok, wait := limiter.Allow("a", start.Add(10*time.Second))
+	if ok {
+		t.Fatal("达到失败上限后应拒绝尝试")
+	}
+	if wait != 50*time.Second {
+		t.Fatalf("等待时长为 %v，期望 50s", wait)
+	}
+	if ok, _ := limiter.Allow("b", start.Add(10*time.Second)); !ok {
+		t.Fatal("其他来源不应受影响")
+	}
+	if ok, _ := limiter.Allow("a", start.Add(time.Minute)); !ok {
+		t.Fatal("时间窗口结束后应允许尝试")
+	}
+
+	limiter.Fail("c", start)
+	limiter.Fail("c", start)
+	limiter.Reset("c")
+	limiter.Fail("c", start)
+	limiter.Fail("c", start)
+	if ok, _ := limiter.Allow("c", start); !ok {
+		t.Fatal("成功后应清除之前的失败记录")
+	}
+}
+
+func TestAttemptLimiterPrune(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewAttemptLimiter(1, time.Minute)
+	for i := 0; i < attemptLimiterPruneSize; i++ {
+		limiter.Fail(fmt.Sprintf("k%d", i), start)
+	}
+	limiter.Fail("new", start.Add(2*time.Minute))
+	if len(limiter.attempts) != 1 {
+		t.Fatalf("过期记录应被清理，剩余 %d 条", len(limiter.attempts))
+	}
+}
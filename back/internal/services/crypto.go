@@ -0,0 +1,456 @@
+package services
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 加密文件格式：
+// 文件头为 magic(4) + 版本(1) + nonce 前缀(8)，随后是若干 AES-256-GCM 加密块。
+// 每块明文最多 encryptChunkSize 字节，nonce 为前缀 + 4 字节块序号，
+// 附加数据标记是否为最后一块，以防止文件被截断后仍能解密。
+const (
+	encryptMagic     = "AHSE"
+	encryptVersion   = 1
+	encryptChunkSize = 64 * 1024
+	noncePrefixSize  = 8
+)
+
+var errNoMasterKey = errors.New("未配置加密主密钥")
+
+// masterKeys 解析当前主密钥及历史主密钥（用于解开旧密钥包装的数据密钥）
+func masterKeys() (string, map[string][]byte, error) {
+	cfg := config.AppConfig.Encryption
+	if cfg.MasterKey == "" {
+		return "", nil, errNoMasterKey
+	}
+	keys := make(map[string][]byte)
+	current, err := decodeMasterKey(cfg.MasterKey)
+	if err != nil {
+		return "", nil, err
+	}
+	keys[cfg.MasterKeyID] = current
+
+	// 历史密钥格式：id:base64,id:base64
+	for _, entry := range strings.Split(cfg.PreviousKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return "", nil, fmt.Errorf("历史主密钥格式错误: %s", entry)
+		}
+		key, err := decodeMasterKey(parts[1])
+		if err != nil {
+			return "", nil, err
+		}
+		keys[parts[0]] = key
+	}
+	return cfg.MasterKeyID, keys, nil
+}
+
+func decodeMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("主密钥必须是 base64 编码的 32 字节密钥")
+	}
+	return key, nil
+}
+
+// EncryptionEnabled 是否已配置加密主密钥
+func EncryptionEnabled() bool {
+	_, _, err := masterKeys()
+	return err == nil
+}
+
+func sealWithKey(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openWithKey(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// WrapDataKey 使用当前主密钥包装数据密钥
+func WrapDataKey(dataKey []byte) (wrapped, keyID string, err error) {
+	currentID, keys, err := masterKeys()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := sealWithKey(keys[currentID], dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), currentID, nil
+}
+
+// UnwrapDataKey 使用对应的主密钥解开数据密钥
+func UnwrapDataKey(wrapped, keyID string) ([]byte, error) {
+	_, keys, err := masterKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("找不到主密钥: %s", keyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return openWithKey(key, sealed)
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	return nonce
+}
+
+func chunkAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// EncryptStream 将 src 按块加密写入 dst
+func EncryptStream(dst io.Writer, src io.Reader, dataKey []byte) error {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	header := append([]byte(encryptMagic), encryptVersion)
+	if _, err := dst.Write(append(header, prefix...)); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, encryptChunkSize)
+	buf := make([]byte, encryptChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		// 读满一块后再探测是否还有数据，以确定是否为最后一块
+		last := n < encryptChunkSize
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			}
+		}
+		sealed := gcm.Seal(nil, chunkNonce(prefix, counter), buf[:n], chunkAAD(last))
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// decryptReader 逐块解密的读取器
+type decryptReader struct {
+	src     *bufio.Reader
+	gcm     cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	plain   []byte
+	done    bool
+}
+
+// NewDecryptReader 返回一个读取时即时解密的读取器
+func NewDecryptReader(src io.Reader, dataKey []byte) (io.Reader, error) {
+	header := make([]byte, len(encryptMagic)+1+noncePrefixSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("读取加密文件头失败: %v", err)
+	}
+	if string(header[:len(encryptMagic)]) != encryptMagic || header[len(encryptMagic)] != encryptVersion {
+		return nil, errors.New("不是有效的加密文件")
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		src:    bufio.NewReaderSize(src, encryptChunkSize+gcm.Overhead()),
+		gcm:    gcm,
+		prefix: header[len(encryptMagic)+1:],
+		buf:    make([]byte, encryptChunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				return 0, io.ErrUnexpectedEOF // 缺少最后一块，文件被截断
+			}
+			return 0, err
+		}
+		last := n < len(r.buf)
+		if !last {
+			if _, peekErr := r.src.Peek(1); peekErr == io.EOF {
+				last = true
+			}
+		}
+		plain, err := r.gcm.Open(r.buf[:0:0], chunkNonce(r.prefix, r.counter), r.buf[:n], chunkAAD(last))
+		if err != nil {
+			return 0, fmt.Errorf("解密失败: %v", err)
+		}
+		r.counter++
+		r.plain = plain
+		r.done = last
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// EncryptOriginal 使用新生成的数据密钥加密素材原始文件，缩略图等派生文件不受影响
+// 调用方负责持久化 Encrypted、WrappedKey、KeyID 字段
+func (s *UploadService) EncryptOriginal(material *models.Material) error {
+	if material.Encrypted {
+		return nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	wrapped, keyID, err := WrapDataKey(dataKey)
+	if err != nil {
+		return err
+	}
+
+	plainPath := filepath.Join(s.uploadPath, material.FilePath)
+	src, err := os.Open(plainPath)
+	if err != nil {
+		return fmt.Errorf("打开原始文件失败: %v", err)
+	}
+	defer src.Close()
+
+	tmpPath := plainPath + ".encrypting"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := EncryptStream(dst, src, dataKey); err != nil {
+		dst.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("加密文件失败: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, plainPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	material.Encrypted = true
+	material.WrappedKey = wrapped
+	material.KeyID = keyID
+	return nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// OpenOriginal 打开素材原始文件，加密文件会在读取时即时解密
+func (s *UploadService) OpenOriginal(material *models.Material) (io.ReadCloser, error) {
+	if material.StorageTier == StorageTierCold {
+		return nil, fmt.Errorf("素材原始文件位于冷存储，请先恢复")
+	}
+	return s.openMaterialFile(material, material.FilePath)
+}
+
+// OpenProcessed 打开处理流程的成品文件，加密素材的成品文件会在读取时即时解密
+func (s *UploadService) OpenProcessed(material *models.Material) (io.ReadCloser, error) {
+	if material.ProcessedPath == "" {
+		return nil, fmt.Errorf("素材没有处理成品")
+	}
+	return s.openMaterialFile(material, material.ProcessedPath)
+}
+
+// OpenPublic 打开去除敏感元数据的公开副本，加密素材的公开副本会在读取时即时解密
+func (s *UploadService) OpenPublic(material *models.Material) (io.ReadCloser, error) {
+	if material.PublicPath == "" {
+		return nil, fmt.Errorf("素材没有公开副本")
+	}
+	return s.openMaterialFile(material, material.PublicPath)
+}
+
+// openMaterialFile 打开素材的原始文件、成品文件或公开副本，三者使用同一数据密钥加密
+func (s *UploadService) openMaterialFile(material *models.Material, relPath string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(s.uploadPath, relPath))
+	if err != nil {
+		return nil, err
+	}
+	if !material.Encrypted {
+		return file, nil
+	}
+	dataKey, err := UnwrapDataKey(material.WrappedKey, material.KeyID)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader, err := NewDecryptReader(file, dataKey)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return readCloser{Reader: reader, Closer: file}, nil
+}
+
+// writeMaterialFile 写入素材的派生文件，加密素材的文件使用其数据密钥加密后写入
+func (s *UploadService) writeMaterialFile(material *models.Material, relPath string, src io.Reader) error {
+	var dataKey []byte
+	if material.Encrypted {
+		key, err := UnwrapDataKey(material.WrappedKey, material.KeyID)
+		if err != nil {
+			return err
+		}
+		dataKey = key
+	}
+
+	dst, err := os.Create(filepath.Join(s.uploadPath, relPath))
+	if err != nil {
+		return err
+	}
+	if dataKey != nil {
+		err = EncryptStream(dst, src, dataKey)
+	} else {
+		_, err = io.Copy(dst, src)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(filepath.Join(s.uploadPath, relPath))
+	}
+	return err
+}
+
+// plainOriginalPath 返回可直接交给图像/视频处理工具的明文文件路径
+// 加密文件会被解密到私有临时目录下仅属主可读写的临时文件，处理完成后需调用 cleanup 删除
+func (s *UploadService) plainOriginalPath(material *models.Material) (path string, cleanup func(), err error) {
+	if !material.Encrypted {
+		return filepath.Join(s.uploadPath, material.FilePath), func() {}, nil
+	}
+	src, err := s.OpenOriginal(material)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	dir, err := s.privateTempDir()
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.CreateTemp(dir, "material-*"+filepath.Ext(material.Filename))
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", nil, err
+	}
+	tmp.Close()
+	return tmp.Name(), func() { _ = os.Remove(tmp.Name()) }, nil
+}
+
+// RotateDataKeys 使用当前主密钥重新包装所有未使用当前主密钥的数据密钥，不重新加密文件内容
+func RotateDataKeys(db *gorm.DB) (int, error) {
+	currentID, _, err := masterKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	var materials []models.Material
+	if err := db.Where("encrypted = ? AND key_id <> ?", true, currentID).Find(&materials).Error; err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, m := range materials {
+		dataKey, err := UnwrapDataKey(m.WrappedKey, m.KeyID)
+		if err != nil {
+			return rotated, fmt.Errorf("素材 %d: %v", m.ID, err)
+		}
+		wrapped, keyID, err := WrapDataKey(dataKey)
+		if err != nil {
+			return rotated, fmt.Errorf("素材 %d: %v", m.ID, err)
+		}
+		if err := db.Model(&models.Material{}).Where("id = ?", m.ID).Updates(map[string]interface{}{
+			"wrapped_key": wrapped,
+			"key_id":      keyID,
+		}).Error; err != nil {
+			return rotated, fmt.Errorf("素材 %d: %v", m.ID, err)
+		}
+		rotated++
+	}
+	return rotated, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func testDataKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encryptBytes(t *testing.T, plain, key []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := EncryptStream(buf, bytes.NewReader(plain), key); err != nil {
+		t.Fatalf("EncryptStream 返回错误: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decryptBytes(sealed, key []byte) ([]byte, error) {
+	reader, err := NewDecryptReader(bytes.NewReader(sealed), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestEncryptStreamRoundTrip(t *testing.T) {
+	key := testDataKey(t)
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"空输入", 0},
+		{"小于一块", 100},
+		{"正好一块", encryptChunkSize},
+		{"块大小的整数倍", 3 * encryptChunkSize},
+		{"跨块", 2*encryptChunkSize + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+			got, err := decryptBytes(encryptBytes(t, plain, key), key)
+			if err != nil {
+				t.Fatalf("解密返回错误: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("解密结果与原文不一致（长度 %d，期望 %d）", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestDecryptReaderRejectsTampering(t *testing.T) {
+	key := testDataKey(t)
+	plain := make([]byte, 3*encryptChunkSize)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+	sealed := encryptBytes(t, plain, key)
+
+	headerSize := len(encryptMagic) + 1 + noncePrefixSize
+	chunkSize := encryptChunkSize + 16
+	chunk := func(i int) []byte {
+		return sealed[headerSize+i*chunkSize : headerSize+(i+1)*chunkSize]
+	}
+	concat := func(parts ...[]byte) []byte {
+		var result []byte
+		for _, part := range parts {
+			result = append(result, part...)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		sealed []byte
+		key    []byte
+	}{
+		{"缺少最后一块", sealed[:headerSize+2*chunkSize], key},
+		{"最后一块被截断", sealed[:len(sealed)-1], key},
+		{"只有文件头", sealed[:headerSize], key},
+		{"块顺序被调换", concat(sealed[:headerSize], chunk(1), chunk(0), chunk(2)), key},
+		{"密文被修改", concat(sealed[:headerSize], chunk(0), chunk(1), []byte{chunk(2)[0] ^ 1}, chunk(2)[1:]), key},
+		{"密钥错误", sealed, testDataKey(t)},
+		{"文件头无效", concat([]byte("XXXX"), sealed[4:]), key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptBytes(tt.sealed, tt.key)
+			if err == nil {
+				t.Fatalf("应返回错误，得到 %d 字节明文", len(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 自定义字段文本取值的最大长度（字符）
+const customFieldMaxTextLength = 2000
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidateCustomFieldSchema 检查工作流的自定义字段定义
+func ValidateCustomFieldSchema(defs []models.CustomFieldDefinition) error {
+	seen := make(map[string]bool)
+	for i := range defs {
+		def := &defs[i]
+		if !customFieldKeyPattern.MatchString(def.Key) {
+			return fmt.Errorf("字段标识 %q 无效，应以小写字母开头，只包含小写字母、数字和下划线", def.Key)
+		}
+		if seen[def.Key] {
+			return fmt.Errorf("字段标识 %s 重复", def.Key)
+		}
+		seen[def.Key] = true
+		if def.Label == "" {
+			def.Label = def.Key
+		}
+		switch def.Type {
+		case models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldPerson:
+			def.Options = nil
+		case models.CustomFieldEnum:
+			if len(def.Options) == 0 {
+				return fmt.Errorf("枚举字段 %s 至少需要一个选项", def.Key)
+			}
+		default:
+			return fmt.Errorf("字段 %s 的类型 %q 无效，可选 text、number、date、enum、person", def.Key, def.Type)
+		}
+	}
+	return nil
+}
+
+// normalizeCustomFieldValue 按字段类型校验并规范化取值
+func normalizeCustomFieldValue(db *gorm.DB, def models.CustomFieldDefinition, value interface{}) (interface{}, error) {
+	switch def.Type {
+	case models.CustomFieldText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 应为文本", def.Label)
+		}
+		s = strings.TrimSpace(s)
+		if utf8.RuneCountInString(s) > customFieldMaxTextLength {
+			return nil, fmt.Errorf("字段 %s 不能超过 %d 个字符", def.Label, customFieldMaxTextLength)
+		}
+		return s, nil
+	case models.CustomFieldNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 应为数字", def.Label)
+		}
+		return n, nil
+	case models.CustomFieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 应为日期", def.Label)
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("字段 %s 的日期格式应为 YYYY-MM-DD", def.Label)
+		}
+		return s, nil
+	case models.CustomFieldEnum:
+		s, ok := value.(string)
+		if ok {
+			for _, option := range def.Options {
+				if s == option {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("字段 %s 只能是 %s 之一", def.Label, strings.Join(def.Options, "、"))
+	case models.CustomFieldPerson:
+		n, ok := value.(float64)
+		if !ok || n <= 0 || n != float64(uint(n)) {
+			return nil, fmt.Errorf("字段 %s 应为用户ID", def.Label)
+		}
+		var count int64
+		db.Model(&models.User{}).Where("id = ?", uint(n)).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("字段 %s 指定的用户不存在", def.Label)
+		}
+		return uint(n), nil
+	}
+	return nil, fmt.Errorf("字段 %s 的类型无效", def.Label)
+}
+
+// isEmptyCustomFieldValue 空值视为未填写
+func isEmptyCustomFieldValue(value interface{}) bool {
+	s, ok := value.(string)
+	return value == nil || (ok && strings.TrimSpace(s) == "")
+}
+
+// ValidateCustomFieldValues 按工作流的字段定义校验取值：不允许未定义的字段，必填字段不能为空。
+// 返回规范化后的取值，空值会被移除
+func ValidateCustomFieldValues(db *gorm.DB, defs []models.CustomFieldDefinition, values map[string]interface{}) (map[string]interface{}, error) {
+	byKey := make(map[string]models.CustomFieldDefinition, len(defs))
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	result := make(map[string]interface{})
+	for key, value := range values {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("未定义的自定义字段 %s", key)
+		}
+		if isEmptyCustomFieldValue(value) {
+			continue
+		}
+		normalized, err := normalizeCustomFieldValue(db, def, value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = normalized
+	}
+	for _, def := range defs {
+		if _, ok := result[def.Key]; def.Required && !ok {
+			return nil, fmt.Errorf("字段 %s 为必填", def.Label)
+		}
+	}
+	return result, nil
+}
+
+// FilterCustomFieldValues 只保留字段定义中仍存在的取值，用于素材更换工作流或工作流修改字段定义后
+func FilterCustomFieldValues(defs []models.CustomFieldDefinition, values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, def := range defs {
+		if value, ok := values[def.Key]; ok {
+			result[def.Key] = value
+		}
+	}
+	return result
+}
+
+// EncodeCustomFieldValues 序列化自定义字段取值，无取值时返回空串
+func EncodeCustomFieldValues(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// customFieldSearchText 自定义字段参与检索的文本，人员字段解析为用户名
+func customFieldSearchText(db *gorm.DB, material *models.Material) string {
+	if material.Workflow == nil {
+		return ""
+	}
+	values := material.CustomFieldValues()
+	var texts []string
+	for _, def := range material.Workflow.CustomFields() {
+		value, ok := values[def.Key]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, v)
+		case float64:
+			if def.Type == models.CustomFieldPerson {
+				var user models.User
+				if err := db.Select("username").First(&user, uint(v)).Error; err == nil {
+					texts = append(texts, user.Username)
+				}
+			} else {
+				texts = append(texts, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+	return strings.Join(texts, " ")
+}
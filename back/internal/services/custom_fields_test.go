@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"ahsfnu-media-cloud/internal/models"
+)
+
+func TestValidateCustomFieldValues(t *testing.T) {
+	schema := []models.CustomFieldDefinition{
+		{Key: "event", Label: "活动名称", Type: models.CustomFieldText, Required: true},
+		{Key: "count", Label: "人数", Type: models.CustomFieldNumber},
+		{Key: "day", Label: "日期", Type: models.CustomFieldDate},
+		{Key: "level", Label: "级别", Type: models.CustomFieldEnum, Options: []string{"校级", "院级"}},
+	}
+	tests := []struct {
+		name    string
+		schema  []models.CustomFieldDefinition
+		values  map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{"没有字段定义且没有取值", nil, map[string]interface{}{}, map[string]interface{}{}, false},
+		{"没有字段定义时不允许取值", nil, map[string]interface{}{"event": "x"}, nil, true},
+		{"缺少必填字段", schema, map[string]interface{}{"count": 3.0}, nil, true},
+		{"必填字段为空串", schema, map[string]interface{}{"event": "  "}, nil, true},
+		{"未定义的字段", schema, map[string]interface{}{"event": "迎新", "other": "x"}, nil, true},
+		{"规范化取值并移除空值", schema,
+			map[string]interface{}{"event": " 迎新晚会 ", "count": 3.0, "day": "2024-09-01", "level": nil},
+			map[string]interface{}{"event": "迎新晚会", "count": 3.0, "day": "2024-09-01"}, false},
+		{"数字类型错误", schema, map[string]interface{}{"event": "迎新", "count": "3"}, nil, true},
+		{"日期格式错误", schema, map[string]interface{}{"event": "迎新", "day": "2024/09/01"}, nil, true},
+		{"枚举值不在选项中", schema, map[string]interface{}{"event": "迎新", "level": "省级"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateCustomFieldValues(nil, tt.schema, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("结果为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
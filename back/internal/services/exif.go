@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ahsfnu-media-cloud/internal/config"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// ExifInfo 从 EXIF 中读取的信息
+type ExifInfo struct {
+	TakenAt   *time.Time // 拍摄时间（DateTimeOriginal，缺失时为 DateTime）
+	Latitude  *float64   // 纬度，南纬为负
+	Longitude *float64   // 经度，西经为负
+}
+
+// EXIF 标签
+const (
+	exifTagDateTime         = 0x0132
+	exifTagExifIFDPointer   = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+	exifTagGPSIFDPointer    = 0x8825
+	gpsTagLatitudeRef       = 0x0001
+	gpsTagLatitude          = 0x0002
+	gpsTagLongitudeRef      = 0x0003
+	gpsTagLongitude         = 0x0004
+)
+
+var errNoExif = errors.New("未找到 EXIF 信息")
+
+// ReadJPEGExif 从 JPEG 文件的 APP1 段中解析 EXIF 信息
+func ReadJPEGExif(path string) (*ExifInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	payload, err := findExifSegment(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	return parseExif(payload)
+}
+
+// findExifSegment 遍历 JPEG 标记段，返回 "Exif\0\0" 之后的 TIFF 数据
+func findExifSegment(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, errors.New("不是 JPEG 文件")
+	}
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if marker != 0xFF {
+			return nil, errNoExif
+		}
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for kind == 0xFF { // 填充字节
+			if kind, err = r.ReadByte(); err != nil {
+				return nil, err
+			}
+		}
+		// SOS 之后为图像数据，EXIF 只会出现在其之前
+		if kind == 0xDA || kind == 0xD9 {
+			return nil, errNoExif
+		}
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(lenBuf[:])) - 2
+		if length < 0 {
+			return nil, errNoExif
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, err
+		}
+		if kind == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return segment[6:], nil
+		}
+	}
+}
+
+// tiffReader 按 TIFF 头声明的字节序读取数据
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func (t *tiffReader) u16(off int) (uint16, bool) {
+	if off < 0 || off+2 > len(t.data) {
+		return 0, false
+	}
+	return t.order.Uint16(t.data[off:]), true
+}
+
+func (t *tiffReader) u32(off int) (uint32, bool) {
+	if off < 0 || off+4 > len(t.data) {
+		return 0, false
+	}
+	return t.order.Uint32(t.data[off:]), true
+}
+
+// ifdEntry IFD 中的一项
+type ifdEntry struct {
+	tag    uint16
+	typ    uint16
+	count  uint32
+	offset int // 值所在位置（值不超过 4 字节时位于条目内部）
+}
+
+// readIFD 读取指定偏移处的 IFD 条目
+func (t *tiffReader) readIFD(off int) map[uint16]ifdEntry {
+	entries := make(map[uint16]ifdEntry)
+	n, ok := t.u16(off)
+	if !ok {
+		return entries
+	}
+	typeSizes := map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+	for i := 0; i < int(n); i++ {
+		base := off + 2 + i*12
+		tag, ok1 := t.u16(base)
+		typ, ok2 := t.u16(base + 2)
+		count, ok3 := t.u32(base + 4)
+		if !ok1 || !ok2 || !ok3 {
+			break
+		}
+		entry := ifdEntry{tag: tag, typ: typ, count: count, offset: base + 8}
+		// count 来自文件内容，按 64 位计算避免溢出
+		if size, known := typeSizes[typ]; known && uint64(size)*uint64(count) > 4 {
+			ptr, _ := t.u32(base + 8)
+			entry.offset = int(ptr)
+		}
+		entries[tag] = entry
+	}
+	return entries
+}
+
+func (t *tiffReader) ascii(e ifdEntry) string {
+	end := e.offset + int(e.count)
+	if e.typ != 2 || e.offset < 0 || end > len(t.data) {
+		return ""
+	}
+	return strings.TrimRight(string(t.data[e.offset:end]), "\x00 ")
+}
+
+// rationals 读取 RATIONAL 类型的值；count 来自文件内容，分配前先确认数据足够
+func (t *tiffReader) rationals(e ifdEntry) ([]float64, bool) {
+	if e.typ != 5 || e.offset < 0 || uint64(e.offset)+uint64(e.count)*8 > uint64(len(t.data)) {
+		return nil, false
+	}
+	values := make([]float64, 0, e.count)
+	for i := 0; i < int(e.count); i++ {
+		num, ok1 := t.u32(e.offset + i*8)
+		den, ok2 := t.u32(e.offset + i*8 + 4)
+		if !ok1 || !ok2 || den == 0 {
+			return nil, false
+		}
+		values = append(values, float64(num)/float64(den))
+	}
+	return values, true
+}
+
+// gpsCoordinate 将度、分、秒及方向转换为带符号的十进制度数
+func (t *tiffReader) gpsCoordinate(gps map[uint16]ifdEntry, valueTag, refTag uint16, negativeRef string, limit float64) *float64 {
+	e, ok := gps[valueTag]
+	if !ok || e.count != 3 {
+		return nil
+	}
+	dms, ok := t.rationals(e)
+	if !ok || len(dms) != 3 {
+		return nil
+	}
+	value := dms[0] + dms[1]/60 + dms[2]/3600
+	if ref, ok := gps[refTag]; ok && t.ascii(ref) == negativeRef {
+		value = -value
+	}
+	if value < -limit || value > limit {
+		return nil
+	}
+	return &value
+}
+
+// parseExif 解析 TIFF 结构的 EXIF 数据
+func parseExif(data []byte) (*ExifInfo, error) {
+	if len(data) < 8 {
+		return nil, errNoExif
+	}
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errNoExif
+	}
+	ifd0Off, _ := t.u32(4)
+	ifd0 := t.readIFD(int(ifd0Off))
+
+	info := &ExifInfo{}
+	dateStr := ""
+	if ptr, ok := ifd0[exifTagExifIFDPointer]; ok {
+		if off, ok := t.u32(ptr.offset); ok {
+			exifIFD := t.readIFD(int(off))
+			if e, ok := exifIFD[exifTagDateTimeOriginal]; ok {
+				dateStr = t.ascii(e)
+			}
+		}
+	}
+	if dateStr == "" {
+		if e, ok := ifd0[exifTagDateTime]; ok {
+			dateStr = t.ascii(e)
+		}
+	}
+	if ptr, ok := ifd0[exifTagGPSIFDPointer]; ok {
+		if off, ok := t.u32(ptr.offset); ok {
+			gps := t.readIFD(int(off))
+			lat := t.gpsCoordinate(gps, gpsTagLatitude, gpsTagLatitudeRef, "S", 90)
+			lng := t.gpsCoordinate(gps, gpsTagLongitude, gpsTagLongitudeRef, "W", 180)
+			if lat != nil && lng != nil {
+				info.Latitude, info.Longitude = lat, lng
+			}
+		}
+	}
+	if dateStr != "" {
+		// EXIF 时间不含时区，按配置的时区解释
+		if taken, err := time.ParseInLocation("2006:01:02 15:04:05", dateStr, config.Location()); err == nil {
+			info.TakenAt = &taken
+		}
+	}
+	return info, nil
+}
+
+// ISO 6709 位置字符串，如 +31.2304+121.4737+010.000/
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)`)
+
+// probeVideoInfo 通过 ffprobe 读取视频容器中的 creation_time 与拍摄位置
+func probeVideoInfo(path string) *ExifInfo {
+	info := &ExifInfo{}
+	out, err := ffmpeg.Probe(path)
+	if err != nil {
+		return info
+	}
+	var probe struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal([]byte(out), &probe); err != nil {
+		return info
+	}
+	tags := probe.Format.Tags
+	if value, ok := tags["creation_time"]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			info.TakenAt = &t
+		}
+	}
+	for _, key := range []string{"location", "com.apple.quicktime.location.ISO6709"} {
+		m := iso6709Pattern.FindStringSubmatch(tags[key])
+		if m == nil {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(m[1], 64)
+		lng, err2 := strconv.ParseFloat(m[2], 64)
+		if err1 == nil && err2 == nil && lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 {
+			info.Latitude, info.Longitude = &lat, &lng
+			break
+		}
+	}
+	return info
+}
+
+// extractCaptureInfo 提取素材的拍摄时间与位置，无法获取的字段为 nil
+func extractCaptureInfo(path, fileType, mimeType string) *ExifInfo {
+	switch {
+	case fileType == "image" && mimeType == "image/jpeg":
+		if info, err := ReadJPEGExif(path); err == nil {
+			return info
+		}
+	case fileType == "video":
+		return probeVideoInfo(path)
+	}
+	return &ExifInfo{}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// buildGPSExif 构造只含 GPS IFD 的小端 TIFF 数据，latCount 为纬度条目声明的值个数
+func buildGPSExif(latCount uint32, lat, lng [3][2]uint32, latRef, lngRef string) []byte {
+	le := binary.LittleEndian
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) { _ = binary.Write(buf, le, v) }
+
+	// TIFF 头，IFD0 位于偏移 8
+	buf.WriteString("II")
+	write(uint16(42))
+	write(uint32(8))
+
+	// IFD0：只有 GPS IFD 指针，GPS IFD 位于 8+2+12+4=26
+	write(uint16(1))
+	write([]uint16{exifTagGPSIFDPointer, 4})
+	write([]uint32{1, 26})
+	write(uint32(0))
+
+	// GPS IFD：4 个条目，数值区位于 26+2+48+4=80
+	write(uint16(4))
+	write([]uint16{gpsTagLatitudeRef, 2})
+	write(uint32(2))
+	buf.WriteString(latRef + "\x00\x00\x00")
+	write([]uint16{gpsTagLatitude, 5})
+	write([]uint32{latCount, 80})
+	write([]uint16{gpsTagLongitudeRef, 2})
+	write(uint32(2))
+	buf.WriteString(lngRef + "\x00\x00\x00")
+	write([]uint16{gpsTagLongitude, 5})
+	write([]uint32{3, 104})
+	write(uint32(0))
+
+	for _, r := range lat {
+		write(r[:])
+	}
+	for _, r := range lng {
+		write(r[:])
+	}
+	return buf.Bytes()
+}
+
+var (
+	testLat = [3][2]uint32{{31, 1}, {18, 1}, {0, 1}}   // 31°18'
+	testLng = [3][2]uint32{{118, 1}, {22, 1}, {30, 1}} // 118°22'30"
+)
+
+func TestParseExifGPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantGPS bool
+		lat     float64
+		lng     float64
+	}{
+		{"北纬东经", buildGPSExif(3, testLat, testLng, "N", "E"), true, 31.3, 118.375},
+		{"南纬西经", buildGPSExif(3, testLat, testLng, "S", "W"), true, -31.3, -118.375},
+		{"值个数不是 3", buildGPSExif(2, testLat, testLng, "N", "E"), false, 0, 0},
+		{"超大的值个数", buildGPSExif(math.MaxUint32, testLat, testLng, "N", "E"), false, 0, 0},
+		{"超大的值个数且乘积溢出", buildGPSExif(0x20000000, testLat, testLng, "N", "E"), false, 0, 0},
+		{"数据被截断", buildGPSExif(3, testLat, testLng, "N", "E")[:90], false, 0, 0},
+		{"分母为 0", buildGPSExif(3, [3][2]uint32{{31, 0}, {0, 1}, {0, 1}}, testLng, "N", "E"), false, 0, 0},
+		{"纬度越界", buildGPSExif(3, [3][2]uint32{{91, 1}, {0, 1}, {0, 1}}, testLng, "N", "E"), false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseExif(tt.data)
+			if err != nil {
+				t.Fatalf("parseExif 返回错误: %v", err)
+			}
+			if !tt.wantGPS {
+				if info.Latitude != nil || info.Longitude != nil {
+					t.Fatalf("不应解析出位置，得到 %v, %v", *info.Latitude, *info.Longitude)
+				}
+				return
+			}
+			if info.Latitude == nil || info.Longitude == nil {
+				t.Fatal("未解析出位置")
+			}
+			if math.Abs(*info.Latitude-tt.lat) > 1e-9 || math.Abs(*info.Longitude-tt.lng) > 1e-9 {
+				t.Fatalf("位置为 %v, %v，期望 %v, %v", *info.Latitude, *info.Longitude, tt.lat, tt.lng)
+			}
+		})
+	}
+}
+
+func TestParseExifInvalidHeader(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("II"), []byte("XX\x2a\x00\x08\x00\x00\x00")} {
+		if _, err := parseExif(data); err == nil {
+			t.Errorf("%q 应返回错误", data)
+		}
+	}
+}
+
+func TestFindExifSegment(t *testing.T) {
+	payload := buildGPSExif(3, testLat, testLng, "N", "E")
+	jpeg := &bytes.Buffer{}
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	_ = binary.Write(jpeg, binary.BigEndian, uint16(len(payload)+8))
+	jpeg.WriteString("Exif\x00\x00")
+	jpeg.Write(payload)
+	jpeg.Write([]byte{0xFF, 0xDA})
+
+	got, err := findExifSegment(bufio.NewReader(bytes.NewReader(jpeg.Bytes())))
+	if err != nil {
+		t.Fatalf("findExifSegment 返回错误: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatal("返回的 TIFF 数据与写入的不一致")
+	}
+
+	if _, err := findExifSegment(bufio.NewReader(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xDA}))); err == nil {
+		t.Fatal("没有 APP1 段时应返回错误")
+	}
+}
+
+func FuzzParseExif(f *testing.F) {
+	f.Add(buildGPSExif(3, testLat, testLng, "N", "E"))
+	f.Add(buildGPSExif(math.MaxUint32, testLat, testLng, "S", "W"))
+	f.Add([]byte("MM\x00\x2a\x00\x00\x00\x08"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = parseExif(data)
+	})
+}
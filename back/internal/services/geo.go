@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 地球平均半径（米）
+const earthRadiusMeters = 6371000.0
+
+// ValidatePolygon 检查多边形顶点（[经度, 纬度]），至少 3 个顶点且坐标在有效范围内
+func ValidatePolygon(vertices [][2]float64) error {
+	if len(vertices) < 3 {
+		return fmt.Errorf("多边形至少需要 3 个顶点")
+	}
+	for _, v := range vertices {
+		if v[0] < -180 || v[0] > 180 || v[1] < -90 || v[1] > 90 {
+			return fmt.Errorf("无效的坐标 [%g, %g]", v[0], v[1])
+		}
+	}
+	return nil
+}
+
+// SetPlacePolygon 保存多边形并更新外接矩形
+func SetPlacePolygon(place *models.Place, vertices [][2]float64) error {
+	if err := ValidatePolygon(vertices); err != nil {
+		return err
+	}
+	data, err := json.Marshal(vertices)
+	if err != nil {
+		return err
+	}
+	place.Polygon = string(data)
+	place.MinLng, place.MinLat = vertices[0][0], vertices[0][1]
+	place.MaxLng, place.MaxLat = vertices[0][0], vertices[0][1]
+	for _, v := range vertices[1:] {
+		place.MinLng = math.Min(place.MinLng, v[0])
+		place.MaxLng = math.Max(place.MaxLng, v[0])
+		place.MinLat = math.Min(place.MinLat, v[1])
+		place.MaxLat = math.Max(place.MaxLat, v[1])
+	}
+	return nil
+}
+
+// PointInPolygon 射线法判断点是否在多边形内
+func PointInPolygon(lat, lng float64, vertices [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+		xi, yi := vertices[i][0], vertices[i][1]
+		xj, yj := vertices[j][0], vertices[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// RadiusBoundingBox 返回以给定点为中心、半径为 radius 米的外接矩形，用于预筛选
+func RadiusBoundingBox(lat, lng, radius float64) (minLat, minLng, maxLat, maxLng float64) {
+	dLat := radius / earthRadiusMeters * 180 / math.Pi
+	dLng := 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > 1e-6 {
+		dLng = math.Min(dLat/cos, 180)
+	}
+	return lat - dLat, lng - dLng, lat + dLat, lng + dLng
+}
+
+// HaversineSQL 计算两点球面距离（米）的 SQL 表达式，参数依次为纬度、纬度、经度
+func HaversineSQL(latColumn, lngColumn string) string {
+	return fmt.Sprintf("%g * 2 * ASIN(SQRT(POWER(SIN(RADIANS(%s - ?) / 2), 2) + COS(RADIANS(?)) * COS(RADIANS(%s)) * POWER(SIN(RADIANS(%s - ?) / 2), 2)))",
+		earthRadiusMeters, latColumn, latColumn, lngColumn)
+}
+
+// TagMaterialPlaces 根据素材的拍摄位置重新标记其所在的命名地点
+func TagMaterialPlaces(db *gorm.DB, material *models.Material) error {
+	if err := db.Where("material_id = ?", material.ID).Delete(&models.MaterialPlace{}).Error; err != nil {
+		return err
+	}
+	if material.Latitude == nil || material.Longitude == nil {
+		return nil
+	}
+	lat, lng := *material.Latitude, *material.Longitude
+
+	var places []models.Place
+	if err := db.Where("min_lat <= ? AND max_lat >= ? AND min_lng <= ? AND max_lng >= ?", lat, lat, lng, lng).Find(&places).Error; err != nil {
+		return err
+	}
+	for i := range places {
+		if PointInPolygon(lat, lng, places[i].Vertices()) {
+			if err := db.Create(&models.MaterialPlace{MaterialID: material.ID, PlaceID: places[i].ID}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RetagPlace 地点创建或范围变化后重新标记落在其中的素材，返回受影响的素材ID（含原先标记的素材）
+func RetagPlace(db *gorm.DB, place *models.Place) ([]uint, error) {
+	var affected []uint
+	if err := db.Model(&models.MaterialPlace{}).Where("place_id = ?", place.ID).Pluck("material_id", &affected).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("place_id = ?", place.ID).Delete(&models.MaterialPlace{}).Error; err != nil {
+		return nil, err
+	}
+
+	var materials []models.Material
+	err := db.Select("id, latitude, longitude").
+		Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", place.MinLat, place.MaxLat, place.MinLng, place.MaxLng).
+		Find(&materials).Error
+	if err != nil {
+		return nil, err
+	}
+	vertices := place.Vertices()
+	for _, m := range materials {
+		if PointInPolygon(*m.Latitude, *m.Longitude, vertices) {
+			if err := db.Create(&models.MaterialPlace{MaterialID: m.ID, PlaceID: place.ID}).Error; err != nil {
+				return nil, err
+			}
+			affected = append(affected, m.ID)
+		}
+	}
+	return affected, nil
+}
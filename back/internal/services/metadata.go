@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/disintegration/imaging"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"gorm.io/gorm"
+)
+
+// ShouldStripMetadata 计算素材实际生效的元数据去除策略
+// 素材自身的设置优先，未设置时继承所属工作流的策略
+func ShouldStripMetadata(material *models.Material, workflow *models.WorkflowGroup) bool {
+	if material.StripMetadata != nil {
+		return *material.StripMetadata
+	}
+	return workflow != nil && workflow.StripMetadata
+}
+
+// carriesMetadata 素材的文件格式是否可能携带 EXIF 等元数据，gif 与 bmp 不携带
+func carriesMetadata(material *models.Material) bool {
+	if material.FileType != "image" {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(material.Filename)) {
+	case ".gif", ".bmp":
+		return false
+	}
+	return true
+}
+
+// StripRequired 素材对外提供时是否需要去除元数据，会读取所属工作流的策略
+func StripRequired(db *gorm.DB, material *models.Material) bool {
+	if !carriesMetadata(material) {
+		return false
+	}
+	var workflow *models.WorkflowGroup
+	if material.WorkflowID != nil {
+		var wf models.WorkflowGroup
+		if err := db.Select("id, strip_metadata").First(&wf, *material.WorkflowID).Error; err == nil {
+			workflow = &wf
+		}
+	}
+	return ShouldStripMetadata(material, workflow)
+}
+
+// OriginalServable 原始文件能否通过 /uploads 直接访问：加密或位于冷存储的原始文件不能直接访问，
+// 需要去除敏感元数据的素材只能直接访问公开副本，原始文件须通过下载接口按权限获取
+func OriginalServable(db *gorm.DB, material *models.Material) bool {
+	if material.Encrypted || material.StorageTier == StorageTierCold {
+		return false
+	}
+	if material.PublicPath != "" {
+		return false
+	}
+	return !StripRequired(db, material)
+}
+
+// SyncPublicCopy 根据策略生成或清理去除敏感元数据的公开副本
+// 原始文件始终保持不变；加密素材的公开副本同样加密存储；调用方负责持久化 material.PublicPath
+func (s *UploadService) SyncPublicCopy(material *models.Material, strip bool) error {
+	if !strip {
+		if material.PublicPath != "" {
+			_ = os.Remove(filepath.Join(s.uploadPath, material.PublicPath))
+			material.PublicPath = ""
+		}
+		return nil
+	}
+	if material.PublicPath != "" {
+		if _, err := os.Stat(filepath.Join(s.uploadPath, material.PublicPath)); err == nil {
+			return nil
+		}
+	}
+	if material.StorageTier == StorageTierCold {
+		return fmt.Errorf("素材原始文件位于冷存储，请先恢复")
+	}
+
+	// 加密的原始文件需先解密到临时文件
+	srcPath, cleanup, err := s.plainOriginalPath(material)
+	if err != nil {
+		return fmt.Errorf("读取原始文件失败: %v", err)
+	}
+	defer cleanup()
+	dir := filepath.Dir(material.FilePath)
+	ext := strings.ToLower(filepath.Ext(material.Filename))
+
+	if !carriesMetadata(material) {
+		// 这些格式不携带 EXIF 信息，无需生成副本
+		return nil
+	}
+	switch material.FileType {
+	case "image":
+		switch ext {
+		case ".webp":
+			// imaging 无法编码 webp，公开副本统一转为 jpg
+			ext = ".jpg"
+		}
+		relPath := filepath.Join(dir, "public_"+strings.TrimSuffix(material.Filename, filepath.Ext(material.Filename))+ext)
+		if err := s.writePublicCopy(material, relPath, srcPath, stripImageMetadata); err != nil {
+			return fmt.Errorf("生成公开副本失败: %v", err)
+		}
+		material.PublicPath = relPath
+	case "video":
+		relPath := filepath.Join(dir, "public_"+material.Filename)
+		if err := s.writePublicCopy(material, relPath, srcPath, stripVideoMetadata); err != nil {
+			return fmt.Errorf("生成公开副本失败: %v", err)
+		}
+		material.PublicPath = relPath
+	}
+	return nil
+}
+
+// writePublicCopy 调用 strip 生成公开副本。加密素材的副本先生成到私有临时目录，
+// 再使用素材的数据密钥加密写入，上传目录中不会留下明文副本
+func (s *UploadService) writePublicCopy(material *models.Material, relPath, srcPath string, strip func(srcPath, dstPath string) error) error {
+	if !material.Encrypted {
+		return strip(srcPath, filepath.Join(s.uploadPath, relPath))
+	}
+	dir, err := s.privateTempDir()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "public-*"+filepath.Ext(relPath))
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := strip(srcPath, tmp.Name()); err != nil {
+		return err
+	}
+	plain, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer plain.Close()
+	return s.writeMaterialFile(material, relPath, plain)
+}
+
+// ApplyMetadataPolicy 读取素材所属工作流并按生效策略同步公开副本
+// 已入库的素材会同时更新 public_path 字段
+func (s *UploadService) ApplyMetadataPolicy(db *gorm.DB, material *models.Material) error {
+	var workflow *models.WorkflowGroup
+	if material.WorkflowID != nil {
+		var wf models.WorkflowGroup
+		if err := db.First(&wf, *material.WorkflowID).Error; err == nil {
+			workflow = &wf
+		}
+	}
+
+	previous := material.PublicPath
+	if err := s.SyncPublicCopy(material, ShouldStripMetadata(material, workflow)); err != nil {
+		return err
+	}
+	if material.ID != 0 && material.PublicPath != previous {
+		return db.Model(&models.Material{}).Where("id = ?", material.ID).Update("public_path", material.PublicPath).Error
+	}
+	return nil
+}
+
+// ApplyWorkflowMetadataPolicy 工作流策略变化后，重新同步其下所有素材的公开副本
+func (s *UploadService) ApplyWorkflowMetadataPolicy(db *gorm.DB, workflowID uint) error {
+	var materials []models.Material
+	if err := db.Where("workflow_id = ?", workflowID).Find(&materials).Error; err != nil {
+		return err
+	}
+	for i := range materials {
+		if err := s.ApplyMetadataPolicy(db, &materials[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripImageMetadata 按 EXIF 方向摆正图片后重新编码，重新编码不会保留任何 EXIF/XMP 信息
+func stripImageMetadata(srcPath, dstPath string) error {
+	img, err := imaging.Open(srcPath, imaging.AutoOrientation(true))
+	if err != nil {
+		return err
+	}
+	return imaging.Save(img, dstPath, imaging.JPEGQuality(92))
+}
+
+// stripVideoMetadata 去除视频容器中的全局与流元数据（GPS、设备信息等），不重新编码
+func stripVideoMetadata(srcPath, dstPath string) error {
+	return ffmpeg.Input(srcPath).
+		Output(dstPath, ffmpeg.KwArgs{"map_metadata": "-1", "c": "copy"}).
+		OverWriteOutput().
+		Run()
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/base64"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestEncryptedUploadLeavesNoPlaintextCopy(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{Encryption: config.EncryptionConfig{
+		MasterKey:   base64.StdEncoding.EncodeToString(testDataKey(t)),
+		MasterKeyID: "test",
+	}}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	dir := t.TempDir()
+	s := &UploadService{uploadPath: dir}
+	if err := os.MkdirAll(filepath.Join(dir, "2024"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	img := imaging.New(16, 16, color.NRGBA{R: 200, A: 255})
+	if err := imaging.Save(img, filepath.Join(dir, "2024", "photo.jpg")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 与上传接口相同的顺序：先加密原始文件，再按策略生成公开副本
+	material := &models.Material{Filename: "photo.jpg", FilePath: filepath.Join("2024", "photo.jpg"), FileType: "image"}
+	if err := s.EncryptOriginal(material); err != nil {
+		t.Fatalf("EncryptOriginal 返回错误: %v", err)
+	}
+	if err := s.SyncPublicCopy(material, true); err != nil {
+		t.Fatalf("SyncPublicCopy 返回错误: %v", err)
+	}
+	if material.PublicPath == "" {
+		t.Fatal("应生成公开副本")
+	}
+
+	// 上传目录（含私有临时目录）中的任何文件都不能被当作图片读取
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if _, decodeErr := imaging.Open(path); decodeErr == nil {
+			t.Errorf("%s 是明文图片", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := s.OpenPublic(material)
+	if err != nil {
+		t.Fatalf("OpenPublic 返回错误: %v", err)
+	}
+	defer reader.Close()
+	if _, _, err := image.Decode(reader); err != nil {
+		t.Fatalf("解密后的公开副本无法解码: %v", err)
+	}
+}
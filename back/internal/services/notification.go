@@ -0,0 +1,48 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 评论中的 @用户名
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
+// NotificationEnabled 用户是否开启了该类型的通知，未设置时默认开启
+func NotificationEnabled(db *gorm.DB, userID uint, notificationType string) bool {
+	var preference models.NotificationPreference
+	if err := db.Where("user_id = ? AND type = ?", userID, notificationType).First(&preference).Error; err != nil {
+		return true
+	}
+	return preference.Enabled
+}
+
+// Notify 发送站内通知；通知自己或接收者关闭了该类型通知时跳过
+func Notify(db *gorm.DB, notification *models.Notification) error {
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return nil
+	}
+	if !NotificationEnabled(db, notification.UserID, notification.Type) {
+		return nil
+	}
+	return db.Create(notification).Error
+}
+
+// ParseMentions 解析内容中 @ 到的用户名，去重并保持出现顺序
+func ParseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		// 句末的标点不属于用户名
+		username := strings.TrimRight(match[1], ".-")
+		if username != "" && !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// 每张图片提取的主色数量
+	paletteSize = 5
+	// 两个主色之间的最小 Lab 距离，避免提取出几乎相同的颜色
+	paletteMinDistance = 12.0
+)
+
+// PaletteColor 图片主色
+type PaletteColor struct {
+	Hex    string
+	R      uint8
+	G      uint8
+	B      uint8
+	Weight float64 // 该颜色所占像素比例
+}
+
+// ExtractPaletteFromFile 从图片文件（通常为缩略图）中提取主色
+func ExtractPaletteFromFile(path string) ([]PaletteColor, error) {
+	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+	return ExtractPalette(img, paletteSize), nil
+}
+
+// applyPalette 根据缩略图（图片缩略图或视频封面）填充素材的主色信息
+func applyPalette(material *models.Material, thumbPath string) {
+	palette, err := ExtractPaletteFromFile(thumbPath)
+	if err != nil || len(palette) == 0 {
+		return
+	}
+	hexes := make([]string, 0, len(palette))
+	material.Colors = make([]models.MaterialColor, 0, len(palette))
+	for _, color := range palette {
+		l, a, b := RGBToLab(color.R, color.G, color.B)
+		hexes = append(hexes, color.Hex)
+		material.Colors = append(material.Colors, models.MaterialColor{
+			Hex:    color.Hex,
+			L:      l,
+			A:      a,
+			B:      b,
+			Weight: color.Weight,
+		})
+	}
+	material.Palette = strings.Join(hexes, ",")
+}
+
+// ExtractPalette 通过颜色量化统计图片中占比最高的若干颜色
+func ExtractPalette(img image.Image, count int) []PaletteColor {
+	// 缩小图片以加快统计速度
+	small := imaging.Fit(img, 64, 64, imaging.Box)
+
+	type bucket struct {
+		r, g, b uint64
+		n       uint64
+	}
+	buckets := make(map[uint16]*bucket)
+	var total uint64
+
+	bounds := small.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := small.PixOffset(x, y)
+			r, g, b, a := small.Pix[i], small.Pix[i+1], small.Pix[i+2], small.Pix[i+3]
+			if a < 128 {
+				continue // 忽略透明像素
+			}
+			// 每个通道保留高 4 位，共 4096 个量化桶
+			key := uint16(r>>4)<<8 | uint16(g>>4)<<4 | uint16(b>>4)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.r += uint64(r)
+			bk.g += uint64(g)
+			bk.b += uint64(b)
+			bk.n++
+			total++
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, bk := range buckets {
+		sorted = append(sorted, bk)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].n > sorted[j].n })
+
+	palette := make([]PaletteColor, 0, count)
+	for _, bk := range sorted {
+		if len(palette) >= count {
+			break
+		}
+		color := PaletteColor{
+			R:      uint8(bk.r / bk.n),
+			G:      uint8(bk.g / bk.n),
+			B:      uint8(bk.b / bk.n),
+			Weight: float64(bk.n) / float64(total),
+		}
+		distinct := true
+		for i := range palette {
+			if ColorDistance(palette[i].R, palette[i].G, palette[i].B, color.R, color.G, color.B) < paletteMinDistance {
+				// 与已选颜色过于接近，合并占比
+				palette[i].Weight += color.Weight
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			color.Hex = fmt.Sprintf("#%02x%02x%02x", color.R, color.G, color.B)
+			palette = append(palette, color)
+		}
+	}
+	sort.SliceStable(palette, func(i, j int) bool { return palette[i].Weight > palette[j].Weight })
+	return palette
+}
+
+// ParseHexColor 解析 #RRGGBB 或 RRGGBB 格式的颜色
+func ParseHexColor(hex string) (r, g, b uint8, err error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("无效的颜色值: %s", hex)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("无效的颜色值: %s", hex)
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), nil
+}
+
+// RGBToLab 将 sRGB 颜色转换为 CIELAB（D65 白点），便于按人眼感知计算色差
+func RGBToLab(r, g, b uint8) (l, a, bb float64) {
+	linear := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	rl, gl, bl := linear(r), linear(g), linear(b)
+
+	x := (rl*0.4124 + gl*0.3576 + bl*0.1805) / 0.95047
+	y := rl*0.2126 + gl*0.7152 + bl*0.0722
+	z := (rl*0.0193 + gl*0.1192 + bl*0.9505) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 0.008856 {
+			return math.Cbrt(t)
+		}
+		return 7.787*t + 16.0/116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// ColorDistance 计算两个颜色在 Lab 空间中的欧氏距离（CIE76 色差）
+func ColorDistance(r1, g1, b1, r2, g2, b2 uint8) float64 {
+	l1, a1, bb1 := RGBToLab(r1, g1, b1)
+	l2, a2, bb2 := RGBToLab(r2, g2, b2)
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (bb1-bb2)*(bb1-bb2))
+}
@@ -0,0 +1,657 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/disintegration/imaging"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"gorm.io/gorm"
+)
+
+// MaxPipelineSteps 处理流程允许的最多步骤数
+const MaxPipelineSteps = 20
+
+// ErrPipelineNotApplicable 素材不属于工作流、工作流未配置处理流程或流程不适用于该类型的素材
+var ErrPipelineNotApplicable = errors.New("处理流程不适用于该素材")
+
+// 各格式的图片编码方式，jpeg 统一记为 jpg
+var pipelineImageFormats = map[string]imaging.Format{
+	"jpg":  imaging.JPEG,
+	"png":  imaging.PNG,
+	"gif":  imaging.GIF,
+	"bmp":  imaging.BMP,
+	"tiff": imaging.TIFF,
+}
+
+// 视频转码的编码参数
+var pipelineVideoFormats = map[string]ffmpeg.KwArgs{
+	"mp4":  {"c:v": "libx264", "preset": "medium", "c:a": "aac", "movflags": "+faststart"},
+	"webm": {"c:v": "libvpx-vp9", "b:v": "0", "c:a": "libopus"},
+}
+
+// 可选的水印位置
+var pipelineWatermarkPositions = map[string]bool{
+	"top_left":     true,
+	"top_right":    true,
+	"bottom_left":  true,
+	"bottom_right": true,
+	"center":       true,
+}
+
+// 只适用于图片或视频的步骤
+var (
+	pipelineImageSteps = map[string]bool{models.PipelineStepResize: true, models.PipelineStepConvert: true, models.PipelineStepWatermark: true}
+	pipelineVideoSteps = map[string]bool{models.PipelineStepTranscode: true}
+)
+
+// DeletePipelineRuns 删除符合条件的处理记录及其步骤结果
+func DeletePipelineRuns(db *gorm.DB, query interface{}, args ...interface{}) error {
+	var runIDs []uint
+	if err := db.Model(&models.PipelineRun{}).Where(query, args...).Pluck("id", &runIDs).Error; err != nil {
+		return err
+	}
+	if len(runIDs) == 0 {
+		return nil
+	}
+	if err := db.Where("run_id IN ?", runIDs).Delete(&models.PipelineStepResult{}).Error; err != nil {
+		return err
+	}
+	return db.Where("id IN ?", runIDs).Delete(&models.PipelineRun{}).Error
+}
+
+// ParsePipelineConfig 解析处理流程配置，空字符串表示不配置处理流程
+func ParsePipelineConfig(raw string) (*models.PipelineConfig, error) {
+	pipeline := &models.PipelineConfig{}
+	if strings.TrimSpace(raw) == "" {
+		return pipeline, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(pipeline); err != nil {
+		return nil, fmt.Errorf("处理流程配置格式错误: %v", err)
+	}
+	return pipeline, nil
+}
+
+// ValidatePipelineConfig 校验处理流程配置并补全默认值；workflowType 为工作流类型，用于检查步骤是否适用。
+// userID、role 为配置处理流程的用户，水印只能使用其可以查看的素材
+func ValidatePipelineConfig(db *gorm.DB, workflowType string, pipeline *models.PipelineConfig, userID uint, role string) error {
+	if len(pipeline.Steps) > MaxPipelineSteps {
+		return fmt.Errorf("处理流程最多 %d 个步骤", MaxPipelineSteps)
+	}
+	for i := range pipeline.Steps {
+		step := &pipeline.Steps[i]
+		prefix := fmt.Sprintf("第 %d 个步骤", i+1)
+		if workflowType == models.WorkflowTypeImageProcessing && pipelineVideoSteps[step.Type] {
+			return fmt.Errorf("%s：图片处理工作流不能包含视频步骤 %s", prefix, step.Type)
+		}
+		if workflowType == models.WorkflowTypeVideoProcessing && pipelineImageSteps[step.Type] {
+			return fmt.Errorf("%s：视频处理工作流不能包含图片步骤 %s", prefix, step.Type)
+		}
+
+		switch step.Type {
+		case models.PipelineStepResize:
+			if step.MaxWidth < 0 || step.MaxHeight < 0 || (step.MaxWidth == 0 && step.MaxHeight == 0) {
+				return fmt.Errorf("%s：请设置有效的最大宽度或高度", prefix)
+			}
+		case models.PipelineStepConvert:
+			step.Format = strings.ToLower(strings.TrimPrefix(step.Format, "."))
+			if step.Format == "jpeg" {
+				step.Format = "jpg"
+			}
+			if _, ok := pipelineImageFormats[step.Format]; !ok {
+				return fmt.Errorf("%s：不支持转换为 %s 格式", prefix, step.Format)
+			}
+			if step.Quality == 0 {
+				step.Quality = 90
+			}
+			if step.Quality < 1 || step.Quality > 100 {
+				return fmt.Errorf("%s：图片质量应在 1-100 之间", prefix)
+			}
+		case models.PipelineStepWatermark:
+			var watermark *models.Material
+			if step.WatermarkMaterialID != 0 {
+				var material models.Material
+				err := db.Select("id, file_type, uploaded_by, is_public, workflow_id").First(&material, step.WatermarkMaterialID).Error
+				if err == nil {
+					watermark = &material
+				}
+			}
+			err := checkWatermarkMaterial(watermark, func(m *models.Material) bool {
+				return CanViewMaterial(db, m, userID, role)
+			})
+			if err != nil {
+				return fmt.Errorf("%s：%v", prefix, err)
+			}
+			if step.Position == "" {
+				step.Position = "bottom_right"
+			}
+			if !pipelineWatermarkPositions[step.Position] {
+				return fmt.Errorf("%s：无效的水印位置 %s", prefix, step.Position)
+			}
+			if step.Opacity == 0 {
+				step.Opacity = 0.6
+			}
+			if step.Scale == 0 {
+				step.Scale = 0.2
+			}
+			if step.Opacity < 0 || step.Opacity > 1 || step.Scale < 0 || step.Scale > 1 {
+				return fmt.Errorf("%s：水印不透明度与比例应在 0-1 之间", prefix)
+			}
+		case models.PipelineStepAutoTag:
+			if len(step.TagIDs) == 0 && step.RejectableTagID == 0 {
+				return fmt.Errorf("%s：请至少设置一个标签", prefix)
+			}
+			tagIDs := append([]uint{}, step.TagIDs...)
+			if step.RejectableTagID != 0 {
+				tagIDs = append(tagIDs, step.RejectableTagID)
+			}
+			if err := ValidateTagIDs(db, tagIDs); err != nil {
+				return fmt.Errorf("%s：%v", prefix, err)
+			}
+		case models.PipelineStepTranscode:
+			step.Format = strings.ToLower(strings.TrimPrefix(step.Format, "."))
+			if step.Format == "" {
+				step.Format = "mp4"
+			}
+			if _, ok := pipelineVideoFormats[step.Format]; !ok {
+				return fmt.Errorf("%s：不支持转码为 %s 格式", prefix, step.Format)
+			}
+			if step.CRF == 0 {
+				step.CRF = 23
+			}
+			if step.CRF < 1 || step.CRF > 51 || step.MaxHeight < 0 {
+				return fmt.Errorf("%s：CRF 应在 1-51 之间，最大高度不能为负数", prefix)
+			}
+		case models.PipelineStepStripMetadata:
+		case models.PipelineStepNotify:
+			for _, role := range step.Roles {
+				if _, ok := models.WorkflowRoleLabels[role]; !ok {
+					return fmt.Errorf("%s：无效的成员角色 %s", prefix, role)
+				}
+			}
+			if len(step.UserIDs) > 0 {
+				var count int64
+				db.Model(&models.User{}).Where("id IN ?", step.UserIDs).Count(&count)
+				if int(count) != len(uniqueIDs(step.UserIDs)) {
+					return fmt.Errorf("%s：通知的用户不存在", prefix)
+				}
+			}
+		default:
+			return fmt.Errorf("%s：未知的步骤类型 %s", prefix, step.Type)
+		}
+	}
+	return nil
+}
+
+// checkWatermarkMaterial 水印素材必须是操作者可以查看的图片；合成后的成品可能公开访问，
+// 不能借此使用他人的私有素材。无权查看与不存在同样处理，不暴露素材是否存在
+func checkWatermarkMaterial(watermark *models.Material, canView func(*models.Material) bool) error {
+	if watermark == nil || !canView(watermark) {
+		return errors.New("水印素材不存在")
+	}
+	if watermark.FileType != "image" {
+		return errors.New("水印素材必须是图片")
+	}
+	return nil
+}
+
+// uniqueIDs 去重
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool)
+	result := []uint{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// pipelineContext 一次处理中各步骤共享的状态
+type pipelineContext struct {
+	db          *gorm.DB
+	material    *models.Material
+	workflow    *models.WorkflowGroup
+	triggeredBy *uint
+	results     []models.PipelineStepResult
+
+	// 图片步骤在内存中依次处理，最后一个图片步骤完成后写入成品文件
+	img     image.Image
+	format  string
+	quality int
+}
+
+// RunPipeline 对素材执行其所属工作流的处理流程，记录每个步骤的结果；
+// 某个步骤失败后，其余步骤标记为跳过。原始文件保持不变，图片与视频的处理结果写入成品文件
+func (s *UploadService) RunPipeline(db *gorm.DB, materialID uint, trigger string, triggeredBy *uint) (*models.PipelineRun, error) {
+	var material models.Material
+	if err := db.First(&material, materialID).Error; err != nil {
+		return nil, err
+	}
+	if material.WorkflowID == nil {
+		return nil, ErrPipelineNotApplicable
+	}
+	var workflow models.WorkflowGroup
+	if err := db.Preload("Members").First(&workflow, *material.WorkflowID).Error; err != nil {
+		return nil, err
+	}
+	pipeline := workflow.Pipeline()
+	if len(pipeline.Steps) == 0 || !workflow.PipelineAppliesTo(material.FileType) {
+		return nil, ErrPipelineNotApplicable
+	}
+	// 批量操作类型的工作流只手动执行
+	if trigger == models.PipelineTriggerUpload && workflow.Type == models.WorkflowTypeBatchOperation {
+		return nil, ErrPipelineNotApplicable
+	}
+
+	run := &models.PipelineRun{
+		WorkflowID:  workflow.ID,
+		MaterialID:  material.ID,
+		Trigger:     trigger,
+		Status:      models.PipelineStatusRunning,
+		TriggeredBy: triggeredBy,
+	}
+	if err := db.Create(run).Error; err != nil {
+		return nil, err
+	}
+
+	ctx := &pipelineContext{db: db, material: &material, workflow: &workflow, triggeredBy: triggeredBy}
+	lastImageStep := -1
+	if material.FileType == "image" {
+		for i, step := range pipeline.Steps {
+			if pipelineImageSteps[step.Type] {
+				lastImageStep = i
+			}
+		}
+	}
+
+	var failure error
+	for i, step := range pipeline.Steps {
+		result := models.PipelineStepResult{RunID: run.ID, Position: i, Type: step.Type}
+		started := time.Now()
+		switch {
+		case failure != nil:
+			result.Status = models.PipelineStatusSkipped
+			result.Message = "前序步骤失败"
+		case pipelineImageSteps[step.Type] && material.FileType != "image":
+			result.Status = models.PipelineStatusSkipped
+			result.Message = "仅适用于图片"
+		case pipelineVideoSteps[step.Type] && material.FileType != "video":
+			result.Status = models.PipelineStatusSkipped
+			result.Message = "仅适用于视频"
+		default:
+			message, err := s.runPipelineStep(ctx, step)
+			if err == nil && i == lastImageStep {
+				err = s.saveProcessedImage(ctx)
+			}
+			if err != nil {
+				failure = fmt.Errorf("%s 失败: %v", step.Type, err)
+				result.Status = models.PipelineStatusFailed
+				result.Message = err.Error()
+			} else {
+				result.Status = models.PipelineStatusSucceeded
+				result.Message = message
+			}
+		}
+		result.DurationMs = time.Since(started).Milliseconds()
+		if err := db.Create(&result).Error; err != nil {
+			log.Printf("记录素材 %d 处理步骤结果失败: %v", material.ID, err)
+		}
+		ctx.results = append(ctx.results, result)
+	}
+
+	now := time.Now()
+	run.Status = models.PipelineStatusSucceeded
+	if failure != nil {
+		run.Status = models.PipelineStatusFailed
+		run.Error = failure.Error()
+	}
+	run.FinishedAt = &now
+	db.Model(run).Updates(map[string]interface{}{"status": run.Status, "error": run.Error, "finished_at": now})
+	run.Steps = ctx.results
+	return run, nil
+}
+
+// RunPipelineAsync 在后台执行处理流程，不适用时静默跳过
+func (s *UploadService) RunPipelineAsync(db *gorm.DB, materialIDs []uint, trigger string, triggeredBy *uint) {
+	go func() {
+		for _, materialID := range materialIDs {
+			run, err := s.RunPipeline(db, materialID, trigger, triggeredBy)
+			switch {
+			case errors.Is(err, ErrPipelineNotApplicable):
+			case err != nil:
+				log.Printf("素材 %d 执行处理流程失败: %v", materialID, err)
+			case run.Status == models.PipelineStatusFailed:
+				log.Printf("素材 %d 处理流程失败: %s", materialID, run.Error)
+			}
+		}
+	}()
+}
+
+// runPipelineStep 执行单个步骤，返回结果说明
+func (s *UploadService) runPipelineStep(ctx *pipelineContext, step models.PipelineStep) (string, error) {
+	material := ctx.material
+	switch step.Type {
+	case models.PipelineStepResize:
+		img, err := s.pipelineImage(ctx)
+		if err != nil {
+			return "", err
+		}
+		bounds := img.Bounds()
+		width, height := bounds.Dx(), bounds.Dy()
+		scale := 1.0
+		if step.MaxWidth > 0 && width > step.MaxWidth {
+			scale = float64(step.MaxWidth) / float64(width)
+		}
+		if step.MaxHeight > 0 && float64(height)*scale > float64(step.MaxHeight) {
+			scale = float64(step.MaxHeight) / float64(height)
+		}
+		if scale >= 1 {
+			return fmt.Sprintf("尺寸 %dx%d 未超过限制", width, height), nil
+		}
+		ctx.img = imaging.Resize(img, int(float64(width)*scale+0.5), int(float64(height)*scale+0.5), imaging.Lanczos)
+		resized := ctx.img.Bounds()
+		return fmt.Sprintf("%dx%d 缩小为 %dx%d", width, height, resized.Dx(), resized.Dy()), nil
+
+	case models.PipelineStepConvert:
+		if _, err := s.pipelineImage(ctx); err != nil {
+			return "", err
+		}
+		ctx.format, ctx.quality = step.Format, step.Quality
+		return "转换为 " + step.Format, nil
+
+	case models.PipelineStepWatermark:
+		img, err := s.pipelineImage(ctx)
+		if err != nil {
+			return "", err
+		}
+		var watermarkMaterial models.Material
+		if err := ctx.db.First(&watermarkMaterial, step.WatermarkMaterialID).Error; err != nil {
+			return "", fmt.Errorf("水印素材不存在")
+		}
+		if watermarkMaterial.StorageTier == StorageTierCold {
+			return "", fmt.Errorf("水印素材位于冷存储，请先恢复")
+		}
+		path, cleanup, err := s.plainOriginalPath(&watermarkMaterial)
+		if err != nil {
+			return "", fmt.Errorf("读取水印失败: %v", err)
+		}
+		defer cleanup()
+		watermark, err := imaging.Open(path, imaging.AutoOrientation(true))
+		if err != nil {
+			return "", fmt.Errorf("读取水印失败: %v", err)
+		}
+		bounds := img.Bounds()
+		width := int(float64(bounds.Dx()) * step.Scale)
+		if width < 1 {
+			width = 1
+		}
+		watermark = imaging.Resize(watermark, width, 0, imaging.Lanczos)
+		// 水印与边缘保持图片短边 2% 的距离
+		margin := bounds.Dx()
+		if bounds.Dy() < margin {
+			margin = bounds.Dy()
+		}
+		margin = margin / 50
+		wb := watermark.Bounds()
+		var x, y int
+		switch step.Position {
+		case "top_left":
+			x, y = margin, margin
+		case "top_right":
+			x, y = bounds.Dx()-wb.Dx()-margin, margin
+		case "bottom_left":
+			x, y = margin, bounds.Dy()-wb.Dy()-margin
+		case "center":
+			x, y = (bounds.Dx()-wb.Dx())/2, (bounds.Dy()-wb.Dy())/2
+		default:
+			x, y = bounds.Dx()-wb.Dx()-margin, bounds.Dy()-wb.Dy()-margin
+		}
+		ctx.img = imaging.Overlay(img, watermark, image.Pt(x, y), step.Opacity)
+		return "已添加水印（" + step.Position + "）", nil
+
+	case models.PipelineStepAutoTag:
+		tagIDs := append([]uint{}, step.TagIDs...)
+		if step.RejectableTagID != 0 && probablyRejectable(material) {
+			tagIDs = append(tagIDs, step.RejectableTagID)
+		}
+		createdBy := material.UploadedBy
+		if ctx.triggeredBy != nil {
+			createdBy = *ctx.triggeredBy
+		}
+		added := 0
+		for _, tagID := range uniqueIDs(tagIDs) {
+			err := ctx.db.Create(&models.MaterialTag{MaterialID: material.ID, TagID: tagID, CreatedBy: createdBy}).Error
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				continue
+			}
+			if err != nil {
+				return "", fmt.Errorf("添加标签失败: %v", err)
+			}
+			added++
+		}
+		if added > 0 {
+			if err := IndexMaterial(ctx.db, material.ID); err != nil {
+				log.Printf("建立素材 %d 检索索引失败: %v", material.ID, err)
+			}
+		}
+		return fmt.Sprintf("添加了 %d 个标签", added), nil
+
+	case models.PipelineStepTranscode:
+		return s.transcodeVideo(ctx, step)
+
+	case models.PipelineStepStripMetadata:
+		strip := true
+		material.StripMetadata = &strip
+		if err := s.SyncPublicCopy(material, true); err != nil {
+			return "", err
+		}
+		err := ctx.db.Model(&models.Material{}).Where("id = ?", material.ID).
+			Updates(map[string]interface{}{"strip_metadata": true, "public_path": material.PublicPath}).Error
+		if err != nil {
+			return "", err
+		}
+		return "公开文件已去除敏感元数据", nil
+
+	case models.PipelineStepNotify:
+		return notifyPipelineDone(ctx, step)
+	}
+	return "", fmt.Errorf("未知的步骤类型 %s", step.Type)
+}
+
+// pipelineImage 获取当前处理中的图片，首次使用时按 EXIF 方向读取原始文件
+func (s *UploadService) pipelineImage(ctx *pipelineContext) (image.Image, error) {
+	if ctx.img != nil {
+		return ctx.img, nil
+	}
+	if ctx.material.StorageTier == StorageTierCold {
+		return nil, fmt.Errorf("素材原始文件位于冷存储，请先恢复")
+	}
+	path, cleanup, err := s.plainOriginalPath(ctx.material)
+	if err != nil {
+		return nil, fmt.Errorf("读取原始文件失败: %v", err)
+	}
+	defer cleanup()
+	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("读取原始文件失败: %v", err)
+	}
+	ctx.img = img
+	return img, nil
+}
+
+// saveProcessedImage 将处理后的图片写入成品文件，未转换格式时沿用原格式（webp 转为 jpg）
+func (s *UploadService) saveProcessedImage(ctx *pipelineContext) error {
+	if ctx.img == nil {
+		return nil
+	}
+	format, quality := ctx.format, ctx.quality
+	if quality == 0 {
+		quality = 92
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(ctx.material.Filename)), ".")
+		switch format {
+		case "jpeg", "webp":
+			format = "jpg"
+		}
+	}
+	imageFormat, ok := pipelineImageFormats[format]
+	if !ok {
+		return fmt.Errorf("不支持保存为 %s 格式", format)
+	}
+	buf := &bytes.Buffer{}
+	if err := imaging.Encode(buf, ctx.img, imageFormat, imaging.JPEGQuality(quality)); err != nil {
+		return fmt.Errorf("编码图片失败: %v", err)
+	}
+	relPath := processedPath(ctx.material, format)
+	if err := s.writeMaterialFile(ctx.material, relPath, buf); err != nil {
+		return fmt.Errorf("保存处理结果失败: %v", err)
+	}
+	return s.setProcessedPath(ctx, relPath)
+}
+
+// transcodeVideo 转码视频并去除容器元数据，可限制最大高度
+func (s *UploadService) transcodeVideo(ctx *pipelineContext, step models.PipelineStep) (string, error) {
+	if ctx.material.StorageTier == StorageTierCold {
+		return "", fmt.Errorf("素材原始文件位于冷存储，请先恢复")
+	}
+	srcPath, cleanup, err := s.plainOriginalPath(ctx.material)
+	if err != nil {
+		return "", fmt.Errorf("读取原始文件失败: %v", err)
+	}
+	defer cleanup()
+
+	args := ffmpeg.KwArgs{"crf": step.CRF, "map_metadata": "-1"}
+	for key, value := range pipelineVideoFormats[step.Format] {
+		args[key] = value
+	}
+	if step.MaxHeight > 0 {
+		// 只缩小不放大，宽度保持偶数
+		args["vf"] = fmt.Sprintf("scale=-2:'min(ih,%d)'", step.MaxHeight)
+	}
+	relPath := processedPath(ctx.material, step.Format)
+	outPath := filepath.Join(s.uploadPath, relPath)
+	if ctx.material.Encrypted {
+		// 加密素材先转码到私有临时目录，加密后再写入成品文件
+		dir, err := s.privateTempDir()
+		if err != nil {
+			return "", err
+		}
+		tmp, err := os.CreateTemp(dir, "transcode-*."+step.Format)
+		if err != nil {
+			return "", err
+		}
+		tmp.Close()
+		outPath = tmp.Name()
+		defer os.Remove(outPath)
+	}
+	err = ffmpeg.Input(srcPath).
+		Output(outPath, args).
+		OverWriteOutput().
+		Run()
+	if err != nil {
+		return "", fmt.Errorf("转码失败: %v", err)
+	}
+	if ctx.material.Encrypted {
+		plain, err := os.Open(outPath)
+		if err != nil {
+			return "", err
+		}
+		err = s.writeMaterialFile(ctx.material, relPath, plain)
+		plain.Close()
+		if err != nil {
+			return "", fmt.Errorf("保存处理结果失败: %v", err)
+		}
+	}
+	if err := s.setProcessedPath(ctx, relPath); err != nil {
+		return "", err
+	}
+	return "转码为 " + step.Format, nil
+}
+
+// probablyRejectable 判断素材是否可能为废片（模糊或曝光严重失衡），阈值与搜索参数 rejectable 相同
+func probablyRejectable(material *models.Material) bool {
+	cfg := config.AppConfig.Quality
+	if material.SharpnessScore != nil && *material.SharpnessScore < cfg.BlurThreshold {
+		return true
+	}
+	if material.ExposureScore != nil && *material.ExposureScore < cfg.ExposureThreshold {
+		return true
+	}
+	return false
+}
+
+// processedPath 成品文件与原始文件位于同一目录，文件名加 processed_ 前缀
+func processedPath(material *models.Material, format string) string {
+	base := strings.TrimSuffix(material.Filename, filepath.Ext(material.Filename))
+	return filepath.Join(filepath.Dir(material.FilePath), "processed_"+base+"."+format)
+}
+
+// setProcessedPath 记录成品文件，格式变化时删除旧的成品文件
+func (s *UploadService) setProcessedPath(ctx *pipelineContext, relPath string) error {
+	if ctx.material.ProcessedPath != "" && ctx.material.ProcessedPath != relPath {
+		_ = os.Remove(filepath.Join(s.uploadPath, ctx.material.ProcessedPath))
+	}
+	ctx.material.ProcessedPath = relPath
+	return ctx.db.Model(&models.Material{}).Where("id = ?", ctx.material.ID).Update("processed_path", relPath).Error
+}
+
+// notifyPipelineDone 通知指定角色的成员与用户，均未设置时通知上传者
+func notifyPipelineDone(ctx *pipelineContext, step models.PipelineStep) (string, error) {
+	recipients := append([]uint{}, step.UserIDs...)
+	for _, role := range step.Roles {
+		if role == models.WorkflowRoleAdmin {
+			recipients = append(recipients, ctx.workflow.CreatedBy)
+		}
+		for _, member := range ctx.workflow.Members {
+			if member.Role == role {
+				recipients = append(recipients, member.UserID)
+			}
+		}
+	}
+	if len(step.Roles) == 0 && len(step.UserIDs) == 0 {
+		recipients = append(recipients, ctx.material.UploadedBy)
+	}
+
+	title := "素材「" + ctx.material.OriginalFilename + "」已在工作流「" + ctx.workflow.Name + "」中完成处理"
+	var summary []string
+	for _, result := range ctx.results {
+		summary = append(summary, result.Type+"："+result.Message)
+	}
+	content := strings.Join(summary, "\n")
+	if step.Message != "" {
+		content = step.Message
+	}
+
+	recipients = uniqueIDs(recipients)
+	for _, userID := range recipients {
+		err := Notify(ctx.db, &models.Notification{
+			UserID:     userID,
+			Type:       models.NotificationPipelineDone,
+			Title:      title,
+			Content:    content,
+			ActorID:    ctx.triggeredBy,
+			MaterialID: &ctx.material.ID,
+			WorkflowID: &ctx.workflow.ID,
+		})
+		if err != nil {
+			return "", fmt.Errorf("发送通知失败: %v", err)
+		}
+	}
+	return fmt.Sprintf("已通知 %d 人", len(recipients)), nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"ahsfnu-media-cloud/internal/models"
+)
+
+func TestCheckWatermarkMaterial(t *testing.T) {
+	workflowID := uint(9)
+	tests := []struct {
+		name         string
+		watermark    *models.Material
+		role         string
+		workflowRole string
+		wantErr      bool
+	}{
+		{"不存在", nil, "user", "", true},
+		{"自己的图片", &models.Material{UploadedBy: 1, FileType: "image"}, "user", "", false},
+		{"他人的公开图片", &models.Material{UploadedBy: 2, FileType: "image", IsPublic: true}, "user", "", false},
+		{"他人的私有图片", &models.Material{UploadedBy: 2, FileType: "image"}, "user", "", true},
+		{"他人私有图片，所在工作流的非成员", &models.Material{UploadedBy: 2, FileType: "image", WorkflowID: &workflowID}, "user", "", true},
+		{"他人私有图片，所在工作流的成员", &models.Material{UploadedBy: 2, FileType: "image", WorkflowID: &workflowID}, "user", models.WorkflowRoleViewer, false},
+		{"系统管理员", &models.Material{UploadedBy: 2, FileType: "image"}, "admin", "", false},
+		{"不是图片", &models.Material{UploadedBy: 1, FileType: "video"}, "user", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			err := checkWatermarkMaterial(tt.watermark, func(m *models.Material) bool {
+				return canViewMaterial(m, 1, tt.role, staticWorkflowRole(tt.workflowRole, &called))
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
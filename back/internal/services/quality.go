@@ -0,0 +1,65 @@
+package services
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// 质量分析前将图片缩放到的最长边，使不同分辨率的图片得分可比
+const qualityAnalyzeSize = 512
+
+// AnalyzeImageQuality 计算图片的清晰度与曝光得分
+// sharpness 为灰度图拉普拉斯响应的方差，数值越低越模糊
+// exposure 为 0-100 的得分，等于未发生过曝/欠曝截断的像素百分比
+func AnalyzeImageQuality(path string) (sharpness, exposure float64, err error) {
+	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	if err != nil {
+		return 0, 0, err
+	}
+	gray := imaging.Grayscale(imaging.Fit(img, qualityAnalyzeSize, qualityAnalyzeSize, imaging.Lanczos))
+	return laplacianVariance(gray), exposureScore(gray), nil
+}
+
+// laplacianVariance 使用 3x3 拉普拉斯核计算边缘响应的方差
+func laplacianVariance(gray *image.NRGBA) float64 {
+	bounds := gray.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+	at := func(x, y int) float64 {
+		return float64(gray.Pix[gray.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+	}
+
+	var sum, sumSq float64
+	n := float64((w - 2) * (h - 2))
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			v := at(x-1, y) + at(x+1, y) + at(x, y-1) + at(x, y+1) - 4*at(x, y)
+			sum += v
+			sumSq += v * v
+		}
+	}
+	mean := sum / n
+	return sumSq/n - mean*mean
+}
+
+// exposureScore 统计亮度直方图两端被截断的像素占比
+func exposureScore(gray *image.NRGBA) float64 {
+	bounds := gray.Bounds()
+	var total, clipped int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			v := gray.Pix[gray.PixOffset(x, y)]
+			if v <= 4 || v >= 251 {
+				clipped++
+			}
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 * (1 - float64(clipped)/float64(total))
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrReviewStatusChanged 素材的审核状态已被他人修改
+var ErrReviewStatusChanged = errors.New("素材审核状态已变化，请刷新后重试")
+
+// reviewTransitions 审核状态允许的流转：退回后可重新提交，已发布可撤回到已通过
+var reviewTransitions = map[string][]string{
+	"":                           {models.ReviewStatusSubmitted},
+	models.ReviewStatusSubmitted: {models.ReviewStatusInReview, models.ReviewStatusApproved, models.ReviewStatusRejected},
+	models.ReviewStatusInReview:  {models.ReviewStatusApproved, models.ReviewStatusRejected},
+	models.ReviewStatusApproved:  {models.ReviewStatusPublished, models.ReviewStatusInReview},
+	models.ReviewStatusRejected:  {models.ReviewStatusSubmitted},
+	models.ReviewStatusPublished: {models.ReviewStatusApproved},
+}
+
+// ReviewTransitionAllowed 审核状态能否从 from 流转到 to
+func ReviewTransitionAllowed(from, to string) bool {
+	for _, status := range reviewTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextReviewStatuses 从当前状态可以流转到的状态
+func NextReviewStatuses(from string) []string {
+	return reviewTransitions[from]
+}
+
+// ChangeReviewStatus 修改素材的审核状态并记录变更；状态在此期间被他人修改时返回 ErrReviewStatusChanged
+// extra 为需要一并更新的其他字段，可为空
+func ChangeReviewStatus(db *gorm.DB, material *models.Material, to, reason string, actorID uint, extra map[string]interface{}) error {
+	from := material.ReviewStatus
+	updates := map[string]interface{}{"review_status": to}
+	for key, value := range extra {
+		updates[key] = value
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Material{}).
+			Where("id = ? AND review_status = ?", material.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReviewStatusChanged
+		}
+		return tx.Create(&models.MaterialReviewLog{
+			MaterialID: material.ID,
+			WorkflowID: material.WorkflowID,
+			FromStatus: from,
+			ToStatus:   to,
+			Reason:     reason,
+			ActorID:    actorID,
+		}).Error
+	})
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"ahsfnu-media-cloud/internal/models"
+)
+
+func TestReviewTransitionAllowed(t *testing.T) {
+	statuses := []string{
+		"",
+		models.ReviewStatusSubmitted,
+		models.ReviewStatusInReview,
+		models.ReviewStatusApproved,
+		models.ReviewStatusRejected,
+		models.ReviewStatusPublished,
+	}
+	allowed := map[[2]string]bool{
+		{"", models.ReviewStatusSubmitted}:                          true,
+		{models.ReviewStatusSubmitted, models.ReviewStatusInReview}: true,
+		{models.ReviewStatusSubmitted, models.ReviewStatusApproved}: true,
+		{models.ReviewStatusSubmitted, models.ReviewStatusRejected}: true,
+		{models.ReviewStatusInReview, models.ReviewStatusApproved}:  true,
+		{models.ReviewStatusInReview, models.ReviewStatusRejected}:  true,
+		{models.ReviewStatusApproved, models.ReviewStatusPublished}: true,
+		{models.ReviewStatusApproved, models.ReviewStatusInReview}:  true,
+		{models.ReviewStatusRejected, models.ReviewStatusSubmitted}: true,
+		{models.ReviewStatusPublished, models.ReviewStatusApproved}: true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := ReviewTransitionAllowed(from, to); got != want {
+				t.Errorf("%q -> %q: 得到 %v，期望 %v", from, to, got, want)
+			}
+		}
+	}
+	if ReviewTransitionAllowed("unknown", models.ReviewStatusSubmitted) {
+		t.Error("未知状态不应允许流转")
+	}
+}
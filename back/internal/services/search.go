@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 全文检索索引
+//
+// 索引存放在 material_searches 表中，tokens 列为 tsvector。
+// 分词在 Go 中完成：中日韩文字按单字与相邻二字切分（二元切分），
+// 其余文字按字母数字切分并转为小写。词元直接以 tsvector/tsquery 字面量写入，
+// 不依赖数据库的分词配置与区域设置。
+
+// 字段权重，决定相关度排序
+const (
+	searchWeightPrimary   = 'A' // 文件名、图注、标签
+	searchWeightSecondary = 'B' // 工作流名称、地点
+	searchWeightTertiary  = 'C' // 描述类文本、自定义字段
+	searchWeightMinor     = 'D' // 上传者
+)
+
+// SearchField 参与检索的字段
+type SearchField struct {
+	Name   string
+	Text   string
+	Weight byte
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// tokenizeRuns 将文本切分为连续的中日韩文字段与字母数字段
+func tokenizeRuns(text string) (runs [][]rune, cjk []bool) {
+	var current []rune
+	currentCJK := false
+	flush := func() {
+		if len(current) > 0 {
+			runs = append(runs, current)
+			cjk = append(cjk, currentCJK)
+			current = nil
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			if !currentCJK {
+				flush()
+			}
+			currentCJK = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if currentCJK {
+				flush()
+			}
+			currentCJK = false
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs, cjk
+}
+
+// TokenizeForIndex 生成索引词元：中日韩文字同时输出单字与二元词
+func TokenizeForIndex(text string) []string {
+	var tokens []string
+	runs, cjk := tokenizeRuns(text)
+	for i, run := range runs {
+		if !cjk[i] {
+			tokens = append(tokens, string(run))
+			continue
+		}
+		for j := range run {
+			tokens = append(tokens, string(run[j]))
+			if j+1 < len(run) {
+				tokens = append(tokens, string(run[j:j+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// TokenizeForQuery 生成查询词元：中日韩文字按二元切分（单字时为单字），字母数字词按前缀匹配
+func TokenizeForQuery(text string) (tokens []string, prefix []bool) {
+	runs, cjk := tokenizeRuns(text)
+	for i, run := range runs {
+		if !cjk[i] {
+			tokens = append(tokens, string(run))
+			prefix = append(prefix, true)
+			continue
+		}
+		if len(run) == 1 {
+			tokens = append(tokens, string(run))
+			prefix = append(prefix, false)
+			continue
+		}
+		for j := 0; j+1 < len(run); j++ {
+			tokens = append(tokens, string(run[j:j+2]))
+			prefix = append(prefix, false)
+		}
+	}
+	return tokens, prefix
+}
+
+func quoteLexeme(token string) string {
+	token = strings.ReplaceAll(token, `\`, `\\`)
+	return "'" + strings.ReplaceAll(token, "'", "''") + "'"
+}
+
+// buildTSVector 生成带位置与权重的 tsvector 字面量
+func buildTSVector(fields []SearchField) string {
+	lexemes := make(map[string][]string)
+	pos := 1
+	for _, field := range fields {
+		for _, token := range TokenizeForIndex(field.Text) {
+			// tsvector 的位置上限为 16383
+			if pos < 16384 {
+				lexemes[token] = append(lexemes[token], fmt.Sprintf("%d%c", pos, field.Weight))
+				pos++
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(lexemes))
+	for k := range lexemes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, quoteLexeme(k)+":"+strings.Join(lexemes[k], ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BuildTSQuery 将关键词转换为 tsquery 字面量，所有词元需同时命中；无有效词元时返回空串
+func BuildTSQuery(keyword string) string {
+	tokens, prefix := TokenizeForQuery(keyword)
+	parts := make([]string, 0, len(tokens))
+	for i, token := range tokens {
+		part := quoteLexeme(token)
+		if prefix[i] {
+			part += ":*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " & ")
+}
+
+// MaterialSearchFields 收集素材参与检索的字段，需预加载 Uploader、Workflow、MaterialTags.Tag 与 Places.Place
+func MaterialSearchFields(material *models.Material) []SearchField {
+	fields := []SearchField{
+		{Name: "original_filename", Text: material.OriginalFilename, Weight: searchWeightPrimary},
+	}
+	if material.Caption != "" {
+		fields = append(fields, SearchField{Name: "caption", Text: material.Caption, Weight: searchWeightPrimary})
+	}
+	if material.Description != "" {
+		fields = append(fields, SearchField{Name: "description", Text: material.Description, Weight: searchWeightTertiary})
+	}
+	tagNames := make([]string, 0, len(material.MaterialTags))
+	for _, mt := range material.MaterialTags {
+		if mt.Tag.ID != 0 {
+			tagNames = append(tagNames, mt.Tag.Name)
+		}
+	}
+	if len(tagNames) > 0 {
+		fields = append(fields, SearchField{Name: "tags", Text: strings.Join(tagNames, " "), Weight: searchWeightPrimary})
+	}
+	if material.Workflow != nil {
+		fields = append(fields,
+			SearchField{Name: "workflow", Text: material.Workflow.Name, Weight: searchWeightSecondary},
+			SearchField{Name: "workflow_description", Text: material.Workflow.Description, Weight: searchWeightTertiary},
+		)
+	}
+	// 去除敏感元数据的素材不公开拍摄位置，地点也不参与检索
+	placeNames := make([]string, 0, len(material.Places))
+	for _, mp := range material.Places {
+		if mp.Place != nil {
+			placeNames = append(placeNames, mp.Place.Name)
+		}
+	}
+	if len(placeNames) > 0 && !ShouldStripMetadata(material, material.Workflow) {
+		fields = append(fields, SearchField{Name: "places", Text: strings.Join(placeNames, " "), Weight: searchWeightSecondary})
+	}
+	if material.Uploader != nil {
+		fields = append(fields, SearchField{Name: "uploader", Text: material.Uploader.Username, Weight: searchWeightMinor})
+	}
+	return fields
+}
+
+// IndexMaterial 重建单个素材的检索索引
+func IndexMaterial(db *gorm.DB, materialID uint) error {
+	var material models.Material
+	err := db.Preload("Uploader").Preload("Workflow").Preload("MaterialTags.Tag").Preload("Places.Place").First(&material, materialID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return RemoveMaterialIndex(db, materialID)
+		}
+		return err
+	}
+
+	fields := MaterialSearchFields(&material)
+	if text := customFieldSearchText(db, &material); text != "" {
+		fields = append(fields, SearchField{Name: "custom_fields", Text: text, Weight: searchWeightTertiary})
+	}
+	texts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		texts = append(texts, f.Text)
+	}
+
+	return db.Exec(`INSERT INTO material_searches (material_id, document, tokens) VALUES (?, ?, CAST(? AS tsvector))
+		ON CONFLICT (material_id) DO UPDATE SET document = EXCLUDED.document, tokens = EXCLUDED.tokens`,
+		material.ID, strings.Join(texts, "\n"), buildTSVector(fields)).Error
+}
+
+// RemoveMaterialIndex 删除素材的检索索引
+func RemoveMaterialIndex(db *gorm.DB, materialID uint) error {
+	return db.Where("material_id = ?", materialID).Delete(&models.MaterialSearch{}).Error
+}
+
+// ReindexMaterials 重建满足条件的素材的检索索引，用于标签、工作流、用户名变化后的批量同步
+func ReindexMaterials(db *gorm.DB, query interface{}, args ...interface{}) error {
+	var ids []uint
+	if err := db.Model(&models.Material{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := IndexMaterial(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReindexMaterialsAsync 在后台重建满足条件的素材的检索索引
+func ReindexMaterialsAsync(db *gorm.DB, query interface{}, args ...interface{}) {
+	go func() {
+		if err := ReindexMaterials(db, query, args...); err != nil {
+			log.Printf("重建检索索引失败: %v", err)
+		}
+	}()
+}
+
+// IndexMissingMaterials 为尚未建立索引的素材补建索引（启动时调用）
+func IndexMissingMaterials(db *gorm.DB) error {
+	return ReindexMaterials(db, "id NOT IN (SELECT material_id FROM material_searches)")
+}
+
+// HighlightMatches 用 <em> 标记文本中与关键词匹配的部分（其余内容做 HTML 转义），未匹配时返回空串
+func HighlightMatches(text, keyword string) string {
+	tokens, _ := TokenizeForQuery(keyword)
+	if text == "" || len(tokens) == 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	found := false
+	for _, token := range tokens {
+		t := []rune(token)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == token {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString("<em>")
+		}
+		b.WriteString(html.EscapeString(string(r)))
+		if marked[i] && (i == len(runes)-1 || !marked[i+1]) {
+			b.WriteString("</em>")
+		}
+	}
+	return b.String()
+}
+
+// MaterialHighlights 返回各字段的命中高亮片段
+func MaterialHighlights(material *models.Material, keyword string) map[string]string {
+	highlights := make(map[string]string)
+	for _, field := range MaterialSearchFields(material) {
+		if h := HighlightMatches(field.Text, keyword); h != "" {
+			highlights[field.Name] = h
+		}
+	}
+	return highlights
+}
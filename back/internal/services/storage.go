@@ -0,0 +1,241 @@
+package services
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ahsfnu-media-cloud/internal/config"
+	"ahsfnu-media-cloud/internal/models"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gorm.io/gorm"
+)
+
+// 存储层级
+const (
+	StorageTierHot  = "hot"
+	StorageTierCold = "cold"
+)
+
+// 恢复状态
+const (
+	RestoreStatusNone      = ""
+	RestoreStatusRestoring = "restoring"
+	RestoreStatusRestored  = "restored"
+	RestoreStatusFailed    = "failed"
+)
+
+// ColdStorage 冷存储后端
+type ColdStorage interface {
+	Put(key string, src io.Reader) error
+	Get(key string, dst io.Writer) error
+	Delete(key string) error
+}
+
+// NewColdStorage 根据配置创建冷存储后端，未配置时返回错误
+func NewColdStorage() (ColdStorage, error) {
+	cfg := config.AppConfig.Storage
+	switch cfg.ColdBackend {
+	case "local":
+		return &localColdStorage{root: cfg.ColdPath}, nil
+	case "s3":
+		sess, err := session.NewSession(&aws.Config{
+			Endpoint:         aws.String(cfg.S3Endpoint),
+			Region:           aws.String(cfg.S3Region),
+			Credentials:      credentials.NewStaticCredentials(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+			S3ForcePathStyle: aws.Bool(true), // 兼容 MinIO 等 S3 兼容服务
+		})
+		if err != nil {
+			return nil, fmt.Errorf("初始化 S3 会话失败: %v", err)
+		}
+		return &s3ColdStorage{sess: sess, bucket: cfg.S3Bucket}, nil
+	default:
+		return nil, fmt.Errorf("未配置冷存储")
+	}
+}
+
+// localColdStorage 以 gzip 压缩的形式保存在另一个本地卷上
+type localColdStorage struct {
+	root string
+}
+
+func (s *localColdStorage) path(key string) string {
+	return filepath.Join(s.root, key+".gz")
+}
+
+func (s *localColdStorage) Put(key string, src io.Reader) error {
+	dstPath := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (s *localColdStorage) Get(key string, dst io.Writer) error {
+	src, err := os.Open(s.path(key))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	_, err = io.Copy(dst, zr)
+	return err
+}
+
+func (s *localColdStorage) Delete(key string) error {
+	return os.Remove(s.path(key))
+}
+
+// s3ColdStorage 保存在 S3 兼容的对象存储中
+type s3ColdStorage struct {
+	sess   *session.Session
+	bucket string
+}
+
+func (s *s3ColdStorage) Put(key string, src io.Reader) error {
+	_, err := s3manager.NewUploader(s.sess).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   src,
+	})
+	return err
+}
+
+func (s *s3ColdStorage) Get(key string, dst io.Writer) error {
+	out, err := s3.New(s.sess).GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+	_, err = io.Copy(dst, out.Body)
+	return err
+}
+
+func (s *s3ColdStorage) Delete(key string) error {
+	_, err := s3.New(s.sess).DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// MoveToCold 将素材原始文件移入冷存储，缩略图与公开副本保留在热存储
+func (s *UploadService) MoveToCold(db *gorm.DB, cold ColdStorage, material *models.Material) error {
+	if material.StorageTier == StorageTierCold {
+		return nil
+	}
+	hotPath := filepath.Join(s.uploadPath, material.FilePath)
+	src, err := os.Open(hotPath)
+	if err != nil {
+		return fmt.Errorf("打开原始文件失败: %v", err)
+	}
+	key := filepath.ToSlash(material.FilePath)
+	err = cold.Put(key, src)
+	src.Close()
+	if err != nil {
+		return fmt.Errorf("写入冷存储失败: %v", err)
+	}
+
+	now := time.Now()
+	if err := db.Model(material).Updates(map[string]interface{}{
+		"storage_tier":   StorageTierCold,
+		"restore_status": RestoreStatusNone,
+		"tiered_at":      &now,
+	}).Error; err != nil {
+		_ = cold.Delete(key)
+		return err
+	}
+	_ = os.Remove(hotPath)
+	return nil
+}
+
+// ClaimRestore 将冷存储中素材的恢复状态置为恢复中，已在恢复中或不在冷存储时返回 false
+// 以条件更新完成检查与设置，并发请求中只有一个能够取得恢复任务
+func ClaimRestore(db *gorm.DB, materialID uint) (bool, error) {
+	result := db.Model(&models.Material{}).
+		Where("id = ? AND storage_tier = ? AND (restore_status IS NULL OR restore_status <> ?)",
+			materialID, StorageTierCold, RestoreStatusRestoring).
+		Update("restore_status", RestoreStatusRestoring)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RestoreToHot 将冷存储中的原始文件取回热存储
+func (s *UploadService) RestoreToHot(db *gorm.DB, cold ColdStorage, material *models.Material) error {
+	if material.StorageTier != StorageTierCold {
+		return nil
+	}
+	hotPath := filepath.Join(s.uploadPath, material.FilePath)
+	if err := os.MkdirAll(filepath.Dir(hotPath), 0755); err != nil {
+		return err
+	}
+	// 先写入临时文件，避免中断后留下不完整的原始文件
+	tmpPath := hotPath + ".restoring"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	key := filepath.ToSlash(material.FilePath)
+	err = cold.Get(key, dst)
+	dst.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("读取冷存储失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, hotPath); err != nil {
+		return err
+	}
+
+	if err := db.Model(material).Updates(map[string]interface{}{
+		"storage_tier":   StorageTierHot,
+		"restore_status": RestoreStatusRestored,
+		"tiered_at":      nil,
+	}).Error; err != nil {
+		return err
+	}
+	_ = cold.Delete(key)
+	return nil
+}
+
+// MoveWorkflowToCold 将工作流下所有热存储素材移入冷存储
+func (s *UploadService) MoveWorkflowToCold(db *gorm.DB, workflowID uint) error {
+	cold, err := NewColdStorage()
+	if err != nil {
+		return err
+	}
+	var materials []models.Material
+	if err := db.Where("workflow_id = ? AND storage_tier = ?", workflowID, StorageTierHot).Find(&materials).Error; err != nil {
+		return err
+	}
+	for i := range materials {
+		if err := s.MoveToCold(db, cold, &materials[i]); err != nil {
+			return fmt.Errorf("素材 %d: %v", materials[i].ID, err)
+		}
+	}
+	return nil
+}
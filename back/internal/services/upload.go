@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/h2non/filetype"
 	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"gorm.io/gorm"
 )
 
 type UploadService struct {
@@ -82,6 +85,9 @@ func (s *UploadService) UploadFile(file *multipart.FileHeader, userID uint, work
 		return nil, fmt.Errorf("提取文件元数据失败: %v", err)
 	}
 
+	// 拍摄时间与位置
+	capture := extractCaptureInfo(filePath, fileType, mimeType)
+
 	// 创建素材记录
 	material := &models.Material{
 		Filename:         filename,
@@ -95,6 +101,18 @@ func (s *UploadService) UploadFile(file *multipart.FileHeader, userID uint, work
 		Duration:         duration,
 		UploadedBy:       userID,
 		WorkflowID:       workflowID,
+		StorageTier:      StorageTierHot,
+		TakenAt:          capture.TakenAt,
+		Latitude:         capture.Latitude,
+		Longitude:        capture.Longitude,
+	}
+
+	// 图片质量评估，失败时不影响上传
+	if fileType == "image" {
+		if sharpness, exposure, err := AnalyzeImageQuality(filePath); err == nil {
+			material.SharpnessScore = &sharpness
+			material.ExposureScore = &exposure
+		}
 	}
 
 	// 如果是图片或视频，生成缩略图
@@ -104,6 +122,7 @@ func (s *UploadService) UploadFile(file *multipart.FileHeader, userID uint, work
 		thumbRelPath := filepath.Join(datePath, thumbName)
 		if err := generateThumbnail(filePath, thumbPath); err == nil {
 			material.ThumbnailPath = thumbRelPath
+			applyPalette(material, thumbPath)
 		}
 	} else if fileType == "video" {
 		thumbName := "thumb_" + strings.TrimSuffix(filename, ext) + ".jpg"
@@ -111,6 +130,7 @@ func (s *UploadService) UploadFile(file *multipart.FileHeader, userID uint, work
 		thumbRelPath := filepath.Join(datePath, thumbName)
 		if err := generateVideoThumbnail(filePath, thumbPath); err == nil {
 			material.ThumbnailPath = thumbRelPath
+			applyPalette(material, thumbPath)
 		}
 	}
 
@@ -178,9 +198,9 @@ func (s *UploadService) extractFileMetadata(filePath string) (fileType, mimeType
 	return fileType, mimeType, width, height, duration, nil
 }
 
-// 生成缩略图
+// 生成缩略图（按 EXIF Orientation 自动摆正方向）
 func generateThumbnail(srcPath, dstPath string) error {
-	img, err := imaging.Open(srcPath)
+	img, err := imaging.Open(srcPath, imaging.AutoOrientation(true))
 	if err != nil {
 		return err
 	}
@@ -217,9 +237,85 @@ func generateVideoThumbnail(srcPath, dstPath string) error {
 }
 
 // GetFileURL 获取文件访问URL
+// 若素材存在去除敏感元数据的公开副本，则返回副本地址
 func (s *UploadService) GetFileURL(material *models.Material) string {
+	if material.Encrypted {
+		// 加密素材的原始文件与公开副本都不能直接访问，由调用方决定是否提供需要登录的下载接口
+		return ""
+	}
+	path := material.FilePath
+	if material.PublicPath != "" {
+		path = material.PublicPath
+	} else if material.StorageTier == StorageTierCold {
+		// 原始文件位于冷存储，需先恢复才能访问
+		return ""
+	}
 	// 将 Windows 路径分隔符替换为 URL 友好的正斜杠
-	clean := strings.ReplaceAll(material.FilePath, "\\", "/")
+	clean := strings.ReplaceAll(path, "\\", "/")
+	return fmt.Sprintf("/uploads/%s", clean)
+}
+
+// privateTempDir 上传目录下的私有临时目录，存放解密或转码过程中的明文临时文件，不对外提供
+func (s *UploadService) privateTempDir() (string, error) {
+	dir := filepath.Join(s.uploadPath, ".tmp")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// LocalPath 返回上传目录下相对路径对应的完整路径
+func (s *UploadService) LocalPath(relPath string) string {
+	return filepath.Join(s.uploadPath, relPath)
+}
+
+// ResolveUpload 解析 /uploads 下的相对路径，返回允许直接访问的文件的完整路径
+// 缩略图可以直接访问，未加密素材的公开副本与处理成品可以直接访问；
+// 原始文件只有满足 OriginalServable 时才能访问，其余文件一律不可访问
+func (s *UploadService) ResolveUpload(db *gorm.DB, urlPath string) (string, bool) {
+	rel := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if rel == "" || strings.HasPrefix(rel, ".") {
+		// 以 . 开头的目录为私有目录（如解密用的临时目录），不对外提供
+		return "", false
+	}
+
+	base := path.Base(rel)
+	switch {
+	case strings.HasPrefix(base, "thumb_"):
+	case strings.HasPrefix(base, "public_"), strings.HasPrefix(base, "processed_"):
+		column := "processed_path"
+		if strings.HasPrefix(base, "public_") {
+			column = "public_path"
+		}
+		var count int64
+		db.Model(&models.Material{}).Where(column+" = ? AND encrypted = ?", filepath.FromSlash(rel), false).Count(&count)
+		if count == 0 {
+			return "", false
+		}
+	default:
+		var material models.Material
+		if err := db.Where("file_path = ?", filepath.FromSlash(rel)).First(&material).Error; err != nil {
+			return "", false
+		}
+		if !OriginalServable(db, &material) {
+			return "", false
+		}
+	}
+
+	fullPath := filepath.Join(s.uploadPath, filepath.FromSlash(rel))
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return fullPath, true
+}
+
+// GetProcessedURL 获取处理流程成品文件的访问URL，加密素材的成品文件不能直接访问，返回空串
+func (s *UploadService) GetProcessedURL(material *models.Material) string {
+	if material.ProcessedPath == "" || material.Encrypted {
+		return ""
+	}
+	clean := strings.ReplaceAll(material.ProcessedPath, "\\", "/")
 	return fmt.Sprintf("/uploads/%s", clean)
 }
 
@@ -233,14 +329,34 @@ func (s *UploadService) GetThumbnailURL(material *models.Material) string {
 }
 
 // DeleteFile 删除文件
+// 冷存储未配置或删除失败时只记录日志，不影响派生文件与素材记录的删除，冷存储中遗留的文件需手动清理
 func (s *UploadService) DeleteFile(material *models.Material) error {
-	fullPath := filepath.Join(s.uploadPath, material.FilePath)
-	_ = os.Remove(fullPath)
+	if material.StorageTier == StorageTierCold {
+		key := filepath.ToSlash(material.FilePath)
+		if cold, err := NewColdStorage(); err != nil {
+			log.Printf("删除素材 %d 的冷存储文件 %s 失败: %v", material.ID, key, err)
+		} else if err := cold.Delete(key); err != nil {
+			log.Printf("删除素材 %d 的冷存储文件 %s 失败: %v", material.ID, key, err)
+		}
+	} else {
+		fullPath := filepath.Join(s.uploadPath, material.FilePath)
+		_ = os.Remove(fullPath)
+	}
 
 	// 删除缩略图（如果有）
 	if material.ThumbnailPath != "" {
 		thumbFullPath := filepath.Join(s.uploadPath, material.ThumbnailPath)
 		_ = os.Remove(thumbFullPath)
 	}
+
+	// 删除公开副本（如果有）
+	if material.PublicPath != "" {
+		_ = os.Remove(filepath.Join(s.uploadPath, material.PublicPath))
+	}
+
+	// 删除处理流程生成的成品文件（如果有）
+	if material.ProcessedPath != "" {
+		_ = os.Remove(filepath.Join(s.uploadPath, material.ProcessedPath))
+	}
 	return nil
 }
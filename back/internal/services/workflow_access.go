@@ -0,0 +1,88 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowMemberCondition 工作流成员（含创建者）可访问的素材条件，参数为用户ID两次；
+// 只包含拥有查看权限的成员角色，与 WorkflowRoleAllows 的判断保持一致
+var WorkflowMemberCondition = "materials.workflow_id IN (SELECT id FROM workflow_groups WHERE created_by = ? " +
+	"UNION SELECT workflow_id FROM workflow_members WHERE user_id = ? AND role IN (" +
+	quotedRoles(RolesWithPermission(models.WorkflowPermView)) + "))"
+
+// RolesWithPermission 拥有某项权限的成员角色，按名称排序
+func RolesWithPermission(permission string) []string {
+	var roles []string
+	for role := range models.WorkflowRolePermissions {
+		if models.WorkflowRoleAllows(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// quotedRoles 将角色拼接为 SQL 字符串列表，角色均为代码中的常量
+func quotedRoles(roles []string) string {
+	return "'" + strings.Join(roles, "', '") + "'"
+}
+
+// WorkflowRole 用户在工作流中的角色：创建者视为管理员，非成员返回空字符串
+func WorkflowRole(db *gorm.DB, workflowID, userID uint) string {
+	var workflow models.WorkflowGroup
+	err := db.Select("id, created_by").Preload("Members", "user_id = ?", userID).First(&workflow, workflowID).Error
+	if err != nil {
+		return ""
+	}
+	return workflow.MemberRole(userID)
+}
+
+// HasWorkflowPermission 用户是否拥有工作流的某项权限，系统管理员拥有全部权限
+func HasWorkflowPermission(db *gorm.DB, workflowID, userID uint, role, permission string) bool {
+	if role == "admin" {
+		return true
+	}
+	return workflowRoleAllows(role, WorkflowRole(db, workflowID, userID), permission)
+}
+
+// workflowRoleAllows 按系统角色与工作流中的角色判断权限
+func workflowRoleAllows(role, workflowRole, permission string) bool {
+	return role == "admin" || models.WorkflowRoleAllows(workflowRole, permission)
+}
+
+// CanViewMaterial 素材所有者、系统管理员与所属工作流的成员可以查看，公开素材所有人可以查看
+func CanViewMaterial(db *gorm.DB, material *models.Material, userID uint, role string) bool {
+	return canViewMaterial(material, userID, role, func(workflowID uint) string {
+		return WorkflowRole(db, workflowID, userID)
+	})
+}
+
+// CanEditMaterial 素材所有者、系统管理员与所属工作流的管理员可以编辑和删除素材
+func CanEditMaterial(db *gorm.DB, material *models.Material, userID uint, role string) bool {
+	return canEditMaterial(material, userID, role, func(workflowID uint) string {
+		return WorkflowRole(db, workflowID, userID)
+	})
+}
+
+// canViewMaterial 查看权限的判断，workflowRole 返回用户在工作流中的角色，只在需要时调用
+func canViewMaterial(material *models.Material, userID uint, role string, workflowRole func(uint) string) bool {
+	if material.UploadedBy == userID || role == "admin" || material.IsPublic {
+		return true
+	}
+	return material.WorkflowID != nil &&
+		workflowRoleAllows(role, workflowRole(*material.WorkflowID), models.WorkflowPermView)
+}
+
+// canEditMaterial 编辑权限的判断，workflowRole 同 canViewMaterial
+func canEditMaterial(material *models.Material, userID uint, role string, workflowRole func(uint) string) bool {
+	if material.UploadedBy == userID || role == "admin" {
+		return true
+	}
+	return material.WorkflowID != nil &&
+		workflowRoleAllows(role, workflowRole(*material.WorkflowID), models.WorkflowPermManage)
+}
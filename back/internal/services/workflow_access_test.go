@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"ahsfnu-media-cloud/internal/models"
+)
+
+// staticWorkflowRole 返回固定工作流角色的查询函数，并记录是否被调用
+func staticWorkflowRole(workflowRole string, called *bool) func(uint) string {
+	return func(uint) string {
+		*called = true
+		return workflowRole
+	}
+}
+
+func TestWorkflowRoleAllowsWithSystemRole(t *testing.T) {
+	permissions := []string{models.WorkflowPermView, models.WorkflowPermUpload, models.WorkflowPermReview, models.WorkflowPermManage}
+	tests := []struct {
+		role         string
+		workflowRole string
+		allow        []bool // 依次为 view、upload、review、manage
+	}{
+		{"admin", "", []bool{true, true, true, true}},
+		{"admin", models.WorkflowRoleViewer, []bool{true, true, true, true}},
+		{"user", models.WorkflowRoleAdmin, []bool{true, true, true, true}},
+		{"user", models.WorkflowRoleReviewer, []bool{true, true, true, false}},
+		{"user", models.WorkflowRolePhotographer, []bool{true, true, false, false}},
+		{"user", models.WorkflowRoleMember, []bool{true, true, false, false}},
+		{"user", models.WorkflowRoleViewer, []bool{true, false, false, false}},
+		{"user", "", []bool{false, false, false, false}},
+		{"user", "owner", []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.workflowRole, func(t *testing.T) {
+			for i, permission := range permissions {
+				if got := workflowRoleAllows(tt.role, tt.workflowRole, permission); got != tt.allow[i] {
+					t.Errorf("%s 权限: 得到 %v，期望 %v", permission, got, tt.allow[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCanViewAndEditMaterial(t *testing.T) {
+	workflowID := uint(9)
+	own := &models.Material{UploadedBy: 1}
+	public := &models.Material{UploadedBy: 2, IsPublic: true}
+	private := &models.Material{UploadedBy: 2}
+	inWorkflow := &models.Material{UploadedBy: 2, WorkflowID: &workflowID}
+	tests := []struct {
+		name         string
+		material     *models.Material
+		role         string
+		workflowRole string
+		view, edit   bool
+	}{
+		{"自己的素材", own, "user", "", true, true},
+		{"他人的公开素材", public, "user", "", true, false},
+		{"他人的私有素材", private, "user", "", false, false},
+		{"系统管理员", private, "admin", "", true, true},
+		{"工作流只读成员", inWorkflow, "user", models.WorkflowRoleViewer, true, false},
+		{"工作流管理员", inWorkflow, "user", models.WorkflowRoleAdmin, true, true},
+		{"无效的成员角色", inWorkflow, "user", "owner", false, false},
+		{"非工作流成员", inWorkflow, "user", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			if got := canViewMaterial(tt.material, 1, tt.role, staticWorkflowRole(tt.workflowRole, &called)); got != tt.view {
+				t.Errorf("查看: 得到 %v，期望 %v", got, tt.view)
+			}
+			if got := canEditMaterial(tt.material, 1, tt.role, staticWorkflowRole(tt.workflowRole, &called)); got != tt.edit {
+				t.Errorf("编辑: 得到 %v，期望 %v", got, tt.edit)
+			}
+			if called && tt.material.WorkflowID == nil {
+				t.Error("素材不属于工作流时不应查询工作流角色")
+			}
+		})
+	}
+}
+
+func TestWorkflowMemberConditionRoles(t *testing.T) {
+	roles := RolesWithPermission(models.WorkflowPermView)
+	if len(roles) != len(models.WorkflowRolePermissions) {
+		t.Fatalf("拥有查看权限的角色为 %v", roles)
+	}
+	want := "role IN ('admin', 'member', 'photographer', 'reviewer', 'viewer')"
+	if !strings.Contains(WorkflowMemberCondition, want) {
+		t.Fatalf("条件为 %s，应包含 %s", WorkflowMemberCondition, want)
+	}
+	if got := RolesWithPermission(models.WorkflowPermManage); len(got) != 1 || got[0] != models.WorkflowRoleAdmin {
+		t.Fatalf("拥有管理权限的角色为 %v", got)
+	}
+}
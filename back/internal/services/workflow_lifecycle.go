@@ -0,0 +1,86 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowArchiveInterval 检查到期工作流的间隔。截止时间已过的工作流在读写检查中
+// 已视为归档（见 IsArchived），定期任务只负责落库，不需要很频繁；查询按 deadline 索引进行
+const WorkflowArchiveInterval = 10 * time.Minute
+
+// ArchiveWorkflow 归档工作流：记录结束时间并停用，归档后工作流及其素材只读
+func ArchiveWorkflow(db *gorm.DB, workflowID uint) error {
+	return db.Model(&models.WorkflowGroup{}).Where("id = ?", workflowID).Updates(map[string]interface{}{
+		"status":    models.WorkflowStatusArchived,
+		"is_active": false,
+		"ended_at":  time.Now(),
+	}).Error
+}
+
+// ReopenWorkflow 重新开启已归档的工作流，清除结束时间；deadline 为新的截止时间，为空时不设截止时间
+func ReopenWorkflow(db *gorm.DB, workflowID uint, deadline *time.Time) error {
+	return db.Model(&models.WorkflowGroup{}).Where("id = ?", workflowID).Updates(map[string]interface{}{
+		"status":    models.WorkflowStatusActive,
+		"is_active": true,
+		"ended_at":  nil,
+		"deadline":  deadline,
+	}).Error
+}
+
+// ArchiveExpiredWorkflows 归档截止时间已过的工作流，结束时间记为截止时间
+func ArchiveExpiredWorkflows(db *gorm.DB) (int64, error) {
+	result := db.Model(&models.WorkflowGroup{}).
+		Where("status = ? AND deadline <= ?", models.WorkflowStatusActive, time.Now()).
+		Updates(map[string]interface{}{
+			"status":    models.WorkflowStatusArchived,
+			"is_active": false,
+			"ended_at":  gorm.Expr("deadline"),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// StartWorkflowArchiver 在后台定期归档到期的工作流
+func StartWorkflowArchiver(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if archived, err := ArchiveExpiredWorkflows(db); err != nil {
+				log.Printf("自动归档到期工作流失败: %v", err)
+			} else if archived > 0 {
+				log.Printf("已自动归档 %d 个到期工作流", archived)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ArchivedWorkflowIDs 已归档工作流ID的子查询，包括截止时间已过但尚未被自动归档的
+func ArchivedWorkflowIDs(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.WorkflowGroup{}).Select("id").
+		Where("status = ? OR deadline <= ?", models.WorkflowStatusArchived, time.Now())
+}
+
+// TagUsedInArchivedWorkflows 标签是否被已归档工作流中的素材使用
+func TagUsedInArchivedWorkflows(db *gorm.DB, tagID uint) bool {
+	var count int64
+	db.Model(&models.MaterialTag{}).
+		Joins("JOIN materials ON materials.id = material_tags.material_id").
+		Where("material_tags.tag_id = ? AND materials.workflow_id IN (?)", tagID, ArchivedWorkflowIDs(db)).
+		Count(&count)
+	return count > 0
+}
+
+// WorkflowReadOnly 工作流是否已归档（只读），工作流不存在时返回 false
+func WorkflowReadOnly(db *gorm.DB, workflowID uint) bool {
+	var workflow models.WorkflowGroup
+	if err := db.Select("id, status, deadline").First(&workflow, workflowID).Error; err != nil {
+		return false
+	}
+	return workflow.IsArchived()
+}
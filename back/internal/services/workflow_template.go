@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ahsfnu-media-cloud/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RenderWorkflowName 根据名称模板生成工作流名称：{year} 年份、{month} 月份、{date} 日期（YYYY-MM-DD）、
+// {semester} 学期（2-7 月为春季，其余为秋季，1 月属于上一年的秋季学期）
+func RenderWorkflowName(pattern string, now time.Time) string {
+	semesterYear, semester := now.Year(), "秋季"
+	switch {
+	case now.Month() == time.January:
+		semesterYear--
+	case now.Month() <= time.July:
+		semester = "春季"
+	}
+	return strings.NewReplacer(
+		"{year}", strconv.Itoa(now.Year()),
+		"{month}", strconv.Itoa(int(now.Month())),
+		"{date}", now.Format("2006-01-02"),
+		"{semester}", strconv.Itoa(semesterYear)+semester,
+	).Replace(pattern)
+}
+
+// ValidateTagIDs 检查标签是否都存在
+func ValidateTagIDs(db *gorm.DB, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	var count int64
+	db.Model(&models.Tag{}).Where("id IN ?", tagIDs).Count(&count)
+	if int(count) != len(uniqueIDs(tagIDs)) {
+		return fmt.Errorf("标签不存在")
+	}
+	return nil
+}
+
+// ValidateTemplateMembers 检查模板成员的用户与角色，去除重复的用户（保留第一次出现的角色），未指定角色时为摄影
+func ValidateTemplateMembers(db *gorm.DB, members []models.WorkflowTemplateMember) ([]models.WorkflowTemplateMember, error) {
+	result := []models.WorkflowTemplateMember{}
+	seen := make(map[uint]bool)
+	var userIDs []uint
+	for _, member := range members {
+		if seen[member.UserID] {
+			continue
+		}
+		seen[member.UserID] = true
+		if member.Role == "" {
+			member.Role = models.WorkflowRolePhotographer
+		}
+		if _, ok := models.WorkflowRoleLabels[member.Role]; !ok {
+			return nil, fmt.Errorf("无效的成员角色 %s", member.Role)
+		}
+		result = append(result, member)
+		userIDs = append(userIDs, member.UserID)
+	}
+	if len(userIDs) > 0 {
+		var count int64
+		db.Model(&models.User{}).Where("id IN ?", userIDs).Count(&count)
+		if int(count) != len(userIDs) {
+			return nil, fmt.Errorf("成员用户不存在")
+		}
+	}
+	return result, nil
+}
+
+// TemplateFromWorkflow 由工作流生成模板（需预加载 Members），工作流创建者作为管理员保留在成员中
+func TemplateFromWorkflow(workflow *models.WorkflowGroup) *models.WorkflowTemplate {
+	members := []models.WorkflowTemplateMember{{UserID: workflow.CreatedBy, Role: models.WorkflowRoleAdmin}}
+	for _, member := range workflow.Members {
+		if member.UserID != workflow.CreatedBy {
+			members = append(members, models.WorkflowTemplateMember{UserID: member.UserID, Role: member.Role})
+		}
+	}
+	data, _ := json.Marshal(members)
+
+	namePattern := workflow.NamePattern
+	if namePattern == "" {
+		namePattern = workflow.Name
+	}
+	return &models.WorkflowTemplate{
+		Name:              workflow.Name,
+		NamePattern:       namePattern,
+		Description:       workflow.Description,
+		Type:              workflow.Type,
+		Color:             workflow.Color,
+		Config:            workflow.Config,
+		StripMetadata:     workflow.StripMetadata,
+		CustomFieldSchema: workflow.CustomFieldSchema,
+		DefaultTags:       workflow.DefaultTags,
+		Members:           string(data),
+	}
+}
+
+// CreateWorkflowFromTemplate 按模板创建工作流，创建者为 createdBy（系统角色为 role）；name 为空时按名称模板生成。
+// 已删除的默认标签与成员会被忽略，处理流程引用的标签或素材已删除时返回错误。
+// 返回新工作流及被添加的成员（不含创建者）
+func CreateWorkflowFromTemplate(db *gorm.DB, template *models.WorkflowTemplate, name string, deadline *time.Time, createdBy uint, role string) (*models.WorkflowGroup, []uint, error) {
+	pipeline, err := ParsePipelineConfig(template.Config)
+	if err == nil {
+		err = ValidatePipelineConfig(db, template.Type, pipeline, createdBy, role)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if name == "" {
+		name = RenderWorkflowName(template.NamePattern, time.Now())
+	}
+	workflow := &models.WorkflowGroup{
+		Name:              name,
+		NamePattern:       template.NamePattern,
+		Description:       template.Description,
+		Type:              template.Type,
+		Color:             template.Color,
+		IsActive:          true,
+		Config:            template.Config,
+		Status:            models.WorkflowStatusActive,
+		StripMetadata:     template.StripMetadata,
+		CustomFieldSchema: template.CustomFieldSchema,
+		Deadline:          deadline,
+		CreatedBy:         createdBy,
+	}
+	if tagIDs := template.DefaultTagIDs(); len(tagIDs) > 0 {
+		var existing []uint
+		db.Model(&models.Tag{}).Where("id IN ?", tagIDs).Order("id ASC").Pluck("id", &existing)
+		if len(existing) > 0 {
+			data, _ := json.Marshal(existing)
+			workflow.DefaultTags = string(data)
+		}
+	}
+
+	var memberIDs []uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workflow).Error; err != nil {
+			return err
+		}
+		for _, member := range template.MemberRoles() {
+			if member.UserID == createdBy {
+				continue
+			}
+			var count int64
+			tx.Model(&models.User{}).Where("id = ?", member.UserID).Count(&count)
+			if count == 0 {
+				continue
+			}
+			err := tx.Create(&models.WorkflowMember{WorkflowID: workflow.ID, UserID: member.UserID, Role: member.Role}).Error
+			if err != nil {
+				return err
+			}
+			memberIDs = append(memberIDs, member.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return workflow, memberIDs, nil
+}